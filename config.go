@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	gkconfig "github.com/gookit/config/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are looked up in the working directory when --config is not given
+var defaultConfigFiles = []string{"yu_tool.yaml", ".yu_tool.toml"}
+
+// applyConfigFile fills flags not set on the command line from a config file
+// Keys are flag names, e.g. "target: ./export" or "min-code-len = 2"
+// Command-line flags override config values, which override built-in defaults
+func applyConfigFile(cmd *cobra.Command, configPath string) error {
+	if configPath == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				configPath = name
+				break
+			}
+		}
+		if configPath == "" {
+			return nil
+		}
+	}
+
+	c := gkconfig.New("yu_tool")
+	c.AddDriver(gkconfig.NewDriver(gkconfig.Yaml, yaml.Unmarshal, yaml.Marshal).WithAliases(gkconfig.Yml))
+	c.AddDriver(gkconfig.NewDriver(gkconfig.Toml, toml.Unmarshal, toml.Marshal))
+	if err := c.LoadFiles(configPath); err != nil {
+		return fmt.Errorf("failed to load config file '%s': %w", configPath, err)
+	}
+	data := c.Data()
+
+	var errs []error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		value, ok := data[flag.Name]
+		if !ok || flag.Changed {
+			return
+		}
+		if err := setFlagFromConfig(cmd.Flags(), flag.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for '%s' in config file '%s': %w", flag.Name, configPath, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// setFlagFromConfig sets a flag from a decoded config value; list values set the flag once per element
+func setFlagFromConfig(flags *pflag.FlagSet, name string, value any) error {
+	if values, ok := value.([]any); ok {
+		for _, v := range values {
+			if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return flags.Set(name, fmt.Sprint(value))
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// locale is the language of help texts and of the CLI's own messages, "zh" or "en", see detectLocale
+// Errors and logs from pkg/yuexport are not localized and stay in English
+var locale = "en"
+
+// detectLocale returns the --locale value in args if any, otherwise the language of
+// LC_ALL, LC_MESSAGES or LANG, the first one set; anything that is not Chinese is "en"
+// args are scanned before cobra parses them so that help texts are already localized
+func detectLocale(args []string) string {
+	if value, found := flagArg(args, "locale"); found {
+		return value
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if strings.HasPrefix(strings.ToLower(value), "zh") {
+				return "zh"
+			}
+			return "en"
+		}
+	}
+	return "en"
+}
+
+// flagArg returns the value of the long flag name in args, given as --name=value or --name value,
+// scanning up to a "--" argument; found is false if the flag is not given
+func flagArg(args []string, name string) (value string, found bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, found := strings.CutPrefix(arg, "--"+name+"="); found {
+			return value, true
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// messages holds the messages the CLI itself prints, by key, in Chinese and English
+// An error from pkg/yuexport wrapped in one of them keeps its English text
+var messages = map[string]struct{ zh, en string }{
+	"invalid-locale":       {"无效的语言：%s（应为 zh 或 en）", "invalid locale: %s (expected zh or en)"},
+	"invalid-error-format": {"无效的错误输出格式：%s（应为 text 或 json）", "invalid error format: %s (expected text or json)"},
+	"schema-with-args":     {"--schema 不能与方案名参数同时使用", "--schema cannot be combined with schema arguments"},
+	"single-schema-only":   {"--zip-output、--sqlite、--bundle 和 --freq-report 只支持单个方案", "--zip-output, --sqlite, --bundle and --freq-report support a single schema only"},
+	"schema-failed":        {"导出方案 '%s' 失败：%w", "failed to export schema '%s': %w"},
+	"schemas-exported":     {"已导出 %d/%d 个方案", "exported %d of %d schemas"},
+	"schemas-succeeded":    {"，成功：%s", ", succeeded: %s"},
+	"schemas-failed":       {"，失败：%s", ", failed: %s"},
+	"warnings-failed":      {"出现 %d 条警告（--fail-on-warnings）：", "%d warning(s) (--fail-on-warnings):"},
+	"other-schemas":        {"已导出最短的方案 %s，其他方案：%s（用 --schema 或方案名参数选择）", "exported the shortest schema %s, other schemas: %s (select one with --schema or a schema argument)"},
+	"files-merged":         {"已将 %d 个文件合并到 %s：%d 条", "merged %d files into %s: %d entries"},
+}
+
+// msg formats the message of key in the current locale
+func msg(key string, args ...any) string {
+	m := messages[key]
+	text := m.en
+	if locale == "zh" {
+		text = m.zh
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// msgErr is msg as an error, wrapping a %w argument
+func msgErr(key string, args ...any) error {
+	m := messages[key]
+	text := m.en
+	if locale == "zh" {
+		text = m.zh
+	}
+	return fmt.Errorf(text, args...)
+}
+
+// englishCommands holds the English Short and Long texts of each command, keyed by command name
+// The Chinese texts are set where the commands are defined
+var englishCommands = map[string]struct{ short, long string }{
+	"yu_tool": {short: "Post-process the releases of the Yuhao input methods"},
+	"export": {
+		short: "Export the roots and quick codes of a Yuhao input method",
+		long:  "Export the roots and quick codes of a Yuhao input method. List several schema names as arguments to export them one after another; combine with --target-per-method to keep their outputs apart.",
+	},
+	"extract": {short: "Extract a Yuhao release zip and print its schema name"},
+	"diff":    {short: "Compare the results of two exports (directories or zips)"},
+	"stats":   {short: "Report entry counts, code lengths and collisions of the text files in an export directory (or zip)"},
+	"merge":   {short: "Merge several exported text files (such as quick_words.txt), sorted and de-duplicated by code"},
+}
+
+// englishFlags holds the English usage of each flag, keyed by "command.flag"
+// The Chinese usages are set where the flags are defined
+var englishFlags = map[string]string{
+	"yu_tool.verbose":          "log details (same as --log-level debug)",
+	"yu_tool.log-level":        "log level: debug, info, warn, error",
+	"yu_tool.quiet":            "only print errors: no progress, stats or warnings (ignored together with --verbose)",
+	"yu_tool.fail-on-warnings": "fail when any warning was logged (even below --log-level) and list all warnings, for strict CI checks",
+	"yu_tool.error-format":     "output format of errors: text, or json (an object with error, stage and file fields, for CI)",
+	"yu_tool.config":           "config file path, by default yu_tool.yaml or .yu_tool.toml in the current directory",
+	"yu_tool.locale":           "language of help texts and CLI messages: zh, en (detected from LC_ALL, LC_MESSAGES and LANG by default, otherwise en); export errors and logs stay in English",
+
+	"export.source":              "path of a Yuhao release zip, an extracted directory, an http(s) URL, or - to read the zip from standard input",
+	"export.sha256":              "expected SHA-256 of the zip file; the export stops if it differs",
+	"export.verify-checksums":    "check every file of the schema directory against CHECKSUMS.txt (sha256sum format) at the release root; the export stops on mismatching or unlisted files",
+	"export.target":              "export directory",
+	"export.template-dir":        "directory templates (<schema>.template.toml and suffixed variants) are read from (by default the schema directory of the source, then the dict directory, then the current directory)",
+	"export.items-dir":           "directory the root and quick text files for template items are read from (the export directory by default)",
+	"export.items-merge-mode":    "how suffixed templates read text files: merge (suffixed and unsuffixed files together, suffixed words first), override (only the most specific file)",
+	"export.template-format":     "format of exported templates: toml, yaml",
+	"export.indent":              "indentation of exported templates: a number of spaces, tab, or 0 for none (the encoder's default when empty)",
+	"export.schema":              "schema to export (the shortest schema name in default.custom.yaml by default)",
+	"export.custom-yaml-path":    "path of the schema list file, relative to the release root",
+	"export.yuhao-path":          "path of the dict directory, relative to the release root",
+	"export.method-suffix-strip": "suffix to strip from the end of the schema name (such as _exp) to find the dict files; may be repeated",
+	"export.version":             "release version, used in template output names and the version field (taken from the source file name by default)",
+	"export.root":                "path of the roots file (CSV), required to export roots; may also be a directory or glob of roots.csv and roots_<suffix>.csv files, exported to roots_<suffix>.txt for each suffix",
+	"export.overwrite":           "overwrite existing files in the export directory; when false, stop before writing and list the conflicting files",
+	"export.export-pinyin":       "also export roots_pinyin.txt (the pinyin in the third CSV column of each root, sorted by root)",
+	"export.target-per-method":   "write outputs to a subdirectory of the export directory named after the input method (keeps several schemas apart)",
+	"export.with-hints":          "write each word of template items as a {word, hint} object, with hints read from the hint_category file of items_meta (such as roots_pinyin)",
+	"export.extract-help":        "write the template help to <template>_help.html next to it and keep only that file name in the template (inline by default)",
+	"export.commands-file":       "file of known key binding commands, one per line, replacing the built-in content_reload and content_next; unknown commands in templates are warned about, fatal with --strict",
+	"export.write-retries":       "retries of output writes after transient errors (such as EAGAIN or EBUSY on network drives), doubling the wait each time",
+	"export.append":              "merge new entries with those already in the quick/pop output files, then sort and de-duplicate (new entries win code conflicts)",
+	"export.incremental":         "do not rewrite output files whose content did not change, keeping their modification time (compared with the files on disk by default, see --prev-manifest)",
+	"export.prev-manifest":       "with --incremental, compare digests with the manifest of the previous export (sha256sum format, paths relative to the manifest's directory) instead of reading the output files on disk; rewritten with the digests of this export when it succeeds, and treated as a first export when missing",
+	"export.clean":               "before exporting, remove old output files this run will not write from the export directory (recognized output names of the stages selected with --only/--skip only)",
+	"export.update":              "update the configversion of the original template files (templates of a zip or URL source are extracted to a temp directory, so set --template-dir to the templates to update)",
+	"export.monotonic-seq":       "keep incrementing the configversion sequence when the date changes instead of restarting at 1",
+	"export.separator":           "separator between code and word in output files (escapes such as \\t are supported)",
+	"export.line-ending":         "line ending of text output files: lf, crlf",
+	"export.final-newline":       "end the last line of text output files with a line ending",
+	"export.comment-char":        "character starting comment lines in the roots CSV; comment and blank lines are skipped",
+	"export.csv-delimiter":       "field delimiter of the roots CSV (one character, escapes such as \\t are supported); quoted fields are supported",
+	"export.sort-by":             "order of quick/pop outputs: code, word, weight",
+	"export.root-sort-by":        "order of roots.txt: code, or word (by the Unicode code point of the root, keeping every code of a root in code order)",
+	"export.sort-items-by":       "order of codes in template items: code, source (first appearance), word (first word); except with source, the words of each code are sorted too",
+	"export.preserve-order":      "do not sort, write in source order (still de-duplicated by code)",
+	"export.multi-word":          "write all words of a code on one line (code\tword1 word2) instead of keeping only the first",
+	"export.combine-words-chars": "also write the chars and words of quick/pop together to quick.txt and pop.txt: also (in addition, the default), only (instead of the separate files)",
+	"export.name-template":       "output file name template (text/template with {{.Category}} {{.Suffix}} {{.Method}} {{.Ext}})",
+	"export.timeout":             "timeout of zip downloads",
+	"export.allow-symlinks":      "recreate symlinks of zip sources (their target must stay inside the extraction directory, otherwise the export fails); skipped with a warning by default",
+	"export.keep-temp":           "keep the extraction directory for debugging",
+	"export.temp-dir":            "parent of the temp directories used to download and extract zips (the system temp directory by default); must exist and be writable",
+	"export.stats-json":          "print export stats as JSON",
+	"export.sqlite":              "also write root, quick and pop entries to this SQLite database",
+	"export.bundle":              "also write root, quick and pop entries to this JSON file (grouped by category and suffix, such as roots or quick_words_tc)",
+	"export.freq-report":         "also write statistics of each output file to this file, computed from the parsed words before de-duplication by code: how many words each code maps to and how many codes each code length has (JSON if it ends with .json, text otherwise)",
+	"export.bundle-only":         "remove the text output files once --bundle is written, keeping only the JSON file (templates are still generated)",
+	"export.zip-output":          "package the export directory into this zip file when done",
+	"export.keep-going":          "continue with the remaining steps when one fails and report all errors at the end",
+	"export.fail-on-empty":       "fail when an output file is empty (only a warning by default)",
+	"export.strict":              "strict checks: write malformed dict lines to rejected.txt and fail on invalid root codes",
+	"export.include-ascii":       "keep words made only of ASCII characters (skipped by default)",
+	"export.normalize-width":     "fold full-width letters, digits and punctuation in words to half-width (codes are not affected)",
+	"export.unicode-normalize":   "Unicode normalization form of words and roots: none, nfc, nfd (codes are not affected)",
+	"export.split-by":            "how chars are told from words: grapheme (by grapheme cluster), rune (by code point)",
+	"export.split":               "how dict lines are split into fields: whitespace (any whitespace), tab (tabs only, keeping spaces in words; exported files are also read strictly by separator for templates)",
+	"export.quick-word-col":      "column of the word in quick dicts (from 1); extra columns are allowed and lines with too few columns are skipped with a warning (0 to follow the columns of the dict header)",
+	"export.quick-code-col":      "column of the code in quick dicts (from 1, 2 by default when --quick-word-col is set)",
+	"export.pop-word-col":        "column of the word in pop dicts (from 1), like --quick-word-col",
+	"export.pop-code-col":        "column of the code in pop dicts (from 1), like --quick-code-col",
+	"export.code-case":           "case of root, quick and pop codes: lower, upper, preserve",
+	"export.code-chars":          "characters allowed in codes besides English letters, ranges supported (such as ;/ or 0-9)",
+	"export.strip-tone-marks":    "remove tone digits from quick/pop codes before validation (ni3hao3 becomes nihao); the value is the set of tone characters, ranges supported (1-5 without a value)",
+	"export.min-code-len":        "minimum length of quick/pop codes (0 for no limit)",
+	"export.max-code-len":        "maximum length of quick/pop codes (0 for no limit)",
+	"export.max-key-len":         "maximum code length of the input method; longer quick/pop codes are source errors: skipped with a warning, fatal with --strict (0 to not check)",
+	"export.max-entries":         "maximum entries written to each root, quick and pop output, the first N after sorting, for small samples (0 for no limit)",
+	"export.ignore-file":         "ignore list file: each line is \"code\" (ignore every entry of the code) or \"code\tword\" (ignore that entry), for roots, quick and pop",
+	"export.code-map":            "code substitution file: each line is \"from\tto\"; a single character is replaced everywhere in a code, ^prefix replaces the start of a code; applied to roots, quick and pop after validation",
+	"export.only":                "only run these export steps (comma separated): root, quick, pop, template",
+	"export.skip":                "skip these export steps (comma separated): root, quick, pop, template",
+	"export.watch":               "watch the dict and template files of the source directory and export again on changes (directory sources only, not with --update)",
+
+	"extract.source":         "path of a Yuhao release zip",
+	"extract.target":         "extraction directory",
+	"extract.allow-symlinks": "recreate symlinks of the zip (their target must stay inside the extraction directory, otherwise it fails); skipped with a warning by default",
+
+	"diff.separator": "separator between code and word in exported files (escapes such as \\t are supported)",
+
+	"stats.separator": "separator between code and word in exported files (escapes such as \\t are supported)",
+	"stats.json":      "print the report as JSON",
+
+	"merge.output":     "output path of the merged file",
+	"merge.separator":  "separator between code and word in the files (escapes such as \\t are supported)",
+	"merge.multi-word": "write all words of a code on one line instead of keeping only the first",
+}
+
+// localize switches the help texts of cmd and its subcommands to English when the locale is "en"
+// Texts without an English entry stay in Chinese
+func localize(cmd *cobra.Command) {
+	if locale != "en" {
+		return
+	}
+	if texts, ok := englishCommands[cmd.Name()]; ok {
+		cmd.Short = texts.short
+		if texts.long != "" {
+			cmd.Long = texts.long
+		}
+	}
+	setUsage := func(flag *pflag.Flag) {
+		if usage, ok := englishFlags[cmd.Name()+"."+flag.Name]; ok {
+			flag.Usage = usage
+		}
+	}
+	cmd.LocalNonPersistentFlags().VisitAll(setUsage)
+	cmd.PersistentFlags().VisitAll(setUsage)
+	for _, sub := range cmd.Commands() {
+		localize(sub)
+	}
+}
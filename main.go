@@ -1,10 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"yu_tool/pkg/yuexport"
 )
 
 func main() {
@@ -14,32 +27,456 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			_ = cmd.Help()
 		},
+		// Errors are printed once by printError below, so --error-format json gets nothing else on stderr
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	var verbose bool
+	var logLevel string
+	var configPath string
+	var quiet bool
+	var errorFormat string
+	var localeFlag string
+	var failOnWarnings bool
+	var warnings warningLog
+
+	// checkErr reports err in --error-format and exits, like cobra.CheckErr
+	checkErr := func(err error) {
+		if err != nil {
+			printError(os.Stderr, err, errorFormat)
+			os.Exit(1)
+		}
 	}
 
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyConfigFile(cmd, configPath); err != nil {
+			return err
+		}
+		if localeFlag != "" && localeFlag != "zh" && localeFlag != "en" {
+			return msgErr("invalid-locale", localeFlag)
+		}
+		if errorFormat != "text" && errorFormat != "json" {
+			return msgErr("invalid-error-format", errorFormat)
+		}
+		// --verbose wins over --quiet; --quiet otherwise hides everything below errors
+		conflict := verbose && quiet
+		if conflict {
+			quiet = false
+		} else if quiet {
+			logLevel = "error"
+		}
+		var collector *warningLog
+		if failOnWarnings {
+			collector = &warnings
+		}
+		if err := setupLogger(verbose, logLevel, collector); err != nil {
+			return err
+		}
+		if conflict {
+			slog.Warn("both --verbose and --quiet are set, ignoring --quiet")
+		}
+		return nil
+	}
+	// Only reached when the command itself succeeded
+	cmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		if failOnWarnings {
+			checkErr(warnings.err())
+		}
+	}
+	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "输出详细日志（等同于 --log-level debug）")
+	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "日志级别：debug、info、warn、error")
+	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "只输出错误：不显示进度、统计和警告（与 --verbose 同时使用时忽略）")
+	cmd.PersistentFlags().BoolVar(&failOnWarnings, "fail-on-warnings", false, "运行中输出过任何警告（即使日志级别高于 warn）时以失败退出，并列出所有警告，便于 CI 严格检查")
+	cmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "出错时的输出格式：text，或 json（输出包含 error、stage、file 字段的 JSON 对象，便于 CI 解析）")
+	cmd.PersistentFlags().StringVar(&localeFlag, "locale", "", "帮助和命令行提示的语言：zh、en（默认根据 LC_ALL、LC_MESSAGES、LANG 判断，无法判断时为 en）；导出过程的错误和日志始终为英文")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "配置文件路径，默认读取当前目录下的 yu_tool.yaml 或 .yu_tool.toml")
+
 	var sourceDir string
-	var targetDir string
-	var rootFile string
-	var updateFlag bool
+	var config yuexport.ExportConfig
+	var overwrite bool
+	var finalNewline bool
+	var statsJSON bool
+	var watch bool
 
 	var exportCmd = &cobra.Command{
-		Use:   "export",
+		Use:   "export [schema...]",
 		Short: "导出宇浩输入法的字根、简码",
+		Long:  "导出宇浩输入法的字根、简码。可以在参数中列出多个方案名依次导出，配合 --target-per-method 避免输出文件互相覆盖。",
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			cobra.CheckErr(export(sourceDir, targetDir, rootFile, updateFlag))
+			config.Separator = yuexport.UnescapeSeparator(config.Separator)
+			config.CSVDelimiter = yuexport.UnescapeSeparator(config.CSVDelimiter)
+			config.NoOverwrite = !overwrite
+			config.NoFinalNewline = !finalNewline
+			config.Quiet = quiet
+			if len(args) > 0 && config.Schema != "" {
+				checkErr(errors.New(msg("schema-with-args")))
+			}
+			if len(args) > 1 && (config.ZipOutput != "" || config.SQLitePath != "" || config.BundlePath != "" || config.FreqReport != "") {
+				checkErr(errors.New(msg("single-schema-only")))
+			}
+			exportOne := func(ctx context.Context, config yuexport.ExportConfig) error {
+				stats, err := yuexport.Export(ctx, sourceDir, config)
+				if stats.TempDir != "" && !quiet {
+					fmt.Fprintf(os.Stderr, "extracted files kept in: %s\n", stats.TempDir)
+				}
+				if len(stats.Others) > 0 && !quiet {
+					fmt.Fprintln(os.Stderr, msg("other-schemas", stats.Schema, strings.Join(stats.Others, ", ")))
+				}
+				if err != nil {
+					return err
+				}
+				if quiet && !statsJSON {
+					return nil
+				}
+				return printExportStats(os.Stdout, stats, statsJSON)
+			}
+			run := func(ctx context.Context) error {
+				if len(args) <= 1 {
+					if len(args) == 1 {
+						config.Schema = args[0]
+					}
+					return exportOne(ctx, config)
+				}
+				return exportSchemas(ctx, args, config, quiet, exportOne)
+			}
+			if watch {
+				watchRun := func(ctx context.Context) error {
+					if !quiet {
+						fmt.Printf("[%s] exporting %s\n", time.Now().Format("15:04:05"), sourceDir)
+					}
+					return run(ctx)
+				}
+				checkErr(yuexport.WatchExport(cmd.Context(), sourceDir, config, watchRun))
+				return
+			}
+			checkErr(run(cmd.Context()))
 		},
 	}
 
-	exportCmd.Flags().StringVarP(&sourceDir, "source", "s", "", "宇浩发布的 zip 文件路径")
+	exportCmd.Flags().StringVarP(&sourceDir, "source", "s", "", "宇浩发布的 zip 文件路径、解压后的目录、http(s) 地址，或 - 表示从标准输入读取 zip")
 	_ = exportCmd.MarkFlagRequired("source")
-	exportCmd.Flags().StringVarP(&targetDir, "target", "t", "./export", "导出路径")
-	exportCmd.Flags().StringVarP(&rootFile, "root", "r", "", "字根文件路径（CSV 格式）")
-	_ = exportCmd.MarkFlagRequired("root")
-	exportCmd.Flags().BoolVarP(&updateFlag, "update", "u", false, "更新原始模板文件的 configversion")
+	exportCmd.Flags().StringVar(&config.SHA256, "sha256", "", "校验 zip 文件的 SHA-256 值，不一致时中止导出")
+	exportCmd.Flags().BoolVar(&config.VerifyChecksums, "verify-checksums", false, "按发布根目录下的 CHECKSUMS.txt（sha256sum 格式）校验 schema 目录中的每个文件，不一致或未列出时中止导出")
+	exportCmd.Flags().StringVarP(&config.TargetPath, "target", "t", "./export", "导出路径")
+	exportCmd.Flags().StringVar(&config.TemplateDir, "template-dir", "", "读取模板（<方案名>.template.toml 及带后缀的变体）的目录（默认依次查找源中的 schema 目录、词典目录，最后是当前目录）")
+	exportCmd.Flags().StringVar(&config.ItemsDir, "items-dir", "", "生成模板时读取字根、简码文本文件的目录（默认为导出路径）")
+	exportCmd.Flags().StringVar(&config.ItemsMerge, "items-merge-mode", "merge", "带后缀模板读取文本文件的方式：merge（合并带后缀与不带后缀的文件，带后缀的字词在前）、override（只读存在的最具体文件）")
+	exportCmd.Flags().StringVar(&config.TemplateFormat, "template-format", "toml", "导出模板的格式：toml、yaml")
+	exportCmd.Flags().StringVar(&config.Indent, "indent", "", "导出模板的缩进：空格数、tab，或 0 表示不缩进（默认使用编码器的默认缩进）")
+	exportCmd.Flags().StringVar(&config.Schema, "schema", "", "要导出的方案名（默认使用 default.custom.yaml 中最短的方案名）")
+	exportCmd.Flags().StringVar(&config.SchemaConfigFile, "custom-yaml-path", "schema/default.custom.yaml", "方案列表文件相对于发布根目录的路径")
+	exportCmd.Flags().StringVar(&config.YuhaoDir, "yuhao-path", "schema/yuhao", "词典目录相对于发布根目录的路径")
+	exportCmd.Flags().StringArrayVar(&config.MethodSuffixStrip, "method-suffix-strip", nil, "从方案名末尾去掉的后缀（如 _exp），用于定位词典文件，可重复指定")
+	exportCmd.Flags().StringVar(&config.Version, "version", "", "发布版本号，用于模板输出文件名和 version 字段（默认从源文件名中提取）")
+	exportCmd.Flags().StringVarP(&config.RootPath, "root", "r", "", "字根文件路径（CSV 格式），导出字根时必填；也可以是包含 roots.csv 和 roots_<后缀>.csv 的目录或通配符，按后缀分别导出 roots_<后缀>.txt")
+	exportCmd.Flags().BoolVar(&overwrite, "overwrite", true, "覆盖导出路径中已存在的文件；为 false 时若有冲突则在写入前中止并列出冲突文件")
+	exportCmd.Flags().BoolVar(&config.ExportPinyin, "export-pinyin", false, "同时导出 roots_pinyin.txt（字根与 CSV 第三列拼音的对应，按字根排序）")
+	exportCmd.Flags().BoolVar(&config.TargetPerMethod, "target-per-method", false, "将输出写入导出路径下以输入法名命名的子目录（导出多个方案时避免互相覆盖）")
+	exportCmd.Flags().BoolVar(&config.WithHints, "with-hints", false, "模板 items 中每个字词输出为 {word, hint} 对象，提示读取自 items_meta 的 hint_category 文件（如 roots_pinyin）")
+	exportCmd.Flags().BoolVar(&config.ExtractHelp, "extract-help", false, "将模板的 help 写入同目录的 <模板名>_help.html，模板中只保留该文件名（默认内联）")
+	exportCmd.Flags().StringVar(&config.CommandsFile, "commands-file", "", "已知快捷键命令列表文件（每行一个，替换内置列表 content_reload、content_next）；模板中未知的命令会警告，--strict 时报错")
+	exportCmd.Flags().IntVar(&config.WriteRetries, "write-retries", 0, "写入输出文件遇到临时错误（如网络盘 EAGAIN、EBUSY）时的重试次数，每次等待时间加倍")
+	exportCmd.Flags().BoolVar(&config.Append, "append", false, "将新条目与简码/顶功输出文件中已有的条目合并后重新排序去重（编码冲突时新条目优先）")
+	exportCmd.Flags().BoolVar(&config.Incremental, "incremental", false, "内容未变化的输出文件不重写，保留其修改时间（默认与磁盘上的文件比较，见 --prev-manifest）")
+	exportCmd.Flags().StringVar(&config.PrevManifest, "prev-manifest", "", "配合 --incremental：与上次导出的清单（sha256sum 格式，路径相对于清单所在目录）比较摘要，而不是读取磁盘上的输出文件；导出成功后用本次的摘要重写该清单，不存在时视为首次导出")
+	exportCmd.Flags().BoolVar(&config.Clean, "clean", false, "导出前删除目标目录中本次不会生成的旧输出文件（只删除 --only/--skip 选中阶段的可识别输出文件名）")
+	exportCmd.Flags().BoolVarP(&config.Update, "update", "u", false, "更新原始模板文件的 configversion（zip 或 URL 来源中的模板解压在临时目录，须用 --template-dir 指定要更新的模板）")
+	exportCmd.Flags().BoolVar(&config.MonotonicSeq, "monotonic-seq", false, "configversion 的序号在日期变化时继续递增，而不是从 1 重新开始")
+	exportCmd.Flags().StringVar(&config.Separator, "separator", `\t`, "输出文件中编码与字词之间的分隔符（支持 \\t 等转义）")
+	exportCmd.Flags().StringVar(&config.LineEnding, "line-ending", "lf", "文本输出文件的换行符：lf、crlf")
+	exportCmd.Flags().BoolVar(&finalNewline, "final-newline", true, "文本输出文件的最后一行末尾是否带换行符")
+	exportCmd.Flags().StringVar(&config.CommentChar, "comment-char", "#", "字根 CSV 文件中注释行的起始字符，注释行和空行会被跳过")
+	exportCmd.Flags().StringVar(&config.CSVDelimiter, "csv-delimiter", ",", "字根 CSV 文件的字段分隔符（单个字符，支持 \\t 等转义），支持带引号的字段")
+	exportCmd.Flags().StringVar(&config.SortBy, "sort-by", "code", "简码/顶功输出的排序方式：code、word、weight")
+	exportCmd.Flags().StringVar(&config.RootSortBy, "root-sort-by", "code", "roots.txt 的排序方式：code（按编码）、word（按字根的 Unicode 码点，同一字根的多个编码都保留并按编码排列）")
+	exportCmd.Flags().StringVar(&config.SortItemsBy, "sort-items-by", "code", "模板 items 中编码的顺序：code（按编码）、source（按首次出现顺序）、word（按首个字词）；除 source 外，每个编码的字词也排序")
+	exportCmd.Flags().BoolVar(&config.PreserveOrder, "preserve-order", false, "不排序，按源文件中的顺序输出（仍按编码去重）")
+	exportCmd.Flags().BoolVar(&config.MultiWord, "multi-word", false, "同一编码的多个字词合并到一行（编码\t字词1 字词2），而不是只保留第一个")
+	exportCmd.Flags().StringVar(&config.Combine, "combine-words-chars", "", "同时将简码/顶功的单字与词组合并写入 quick.txt、pop.txt：also（额外写入，默认）、only（不再写分开的文件）")
+	exportCmd.Flags().Lookup("combine-words-chars").NoOptDefVal = "also"
+	exportCmd.Flags().StringVar(&config.NameTemplate, "name-template", "", "输出文件名模板（text/template，可用 {{.Category}} {{.Suffix}} {{.Method}} {{.Ext}}）")
+	exportCmd.Flags().DurationVar(&config.Timeout, "timeout", time.Minute, "下载 zip 文件的超时时间")
+	exportCmd.Flags().BoolVar(&config.KeepTemp, "keep-temp", false, "保留解压的临时目录以便调试")
+	exportCmd.Flags().BoolVar(&config.AllowSymlinks, "allow-symlinks", false, "还原 zip 源中的符号链接（目标须位于解压目录内，否则报错）；默认跳过并警告")
+	exportCmd.Flags().StringVar(&config.TempDir, "temp-dir", "", "下载和解压 zip 时使用的临时目录的父目录（默认使用系统临时目录），需已存在且可写")
+	exportCmd.Flags().BoolVar(&statsJSON, "stats-json", false, "以 JSON 格式输出导出统计")
+	exportCmd.Flags().StringVar(&config.SQLitePath, "sqlite", "", "同时将字根、简码、顶功条目写入该 SQLite 数据库")
+	exportCmd.Flags().StringVar(&config.BundlePath, "bundle", "", "同时将字根、简码、顶功条目写入该 JSON 文件（按类别和后缀分组，如 roots、quick_words_tc）")
+	exportCmd.Flags().StringVar(&config.FreqReport, "freq-report", "", "同时将各输出文件的统计写入该文件（按去重前解析出的字词计算）：每个编码对应字词数的分布、各码长的编码数（以 .json 结尾时为 JSON，否则为文本）")
+	exportCmd.Flags().BoolVar(&config.BundleOnly, "bundle-only", false, "写入 --bundle 后删除文本输出文件，只保留 JSON 文件（模板仍照常生成）")
+	exportCmd.Flags().StringVar(&config.ZipOutput, "zip-output", "", "导出完成后将导出目录打包为该 zip 文件")
+	exportCmd.Flags().BoolVar(&config.KeepGoing, "keep-going", false, "某一步导出失败时继续执行后续步骤，最后汇总报告所有错误")
+	exportCmd.Flags().BoolVar(&config.FailOnEmpty, "fail-on-empty", false, "有导出文件为空时报错（默认只输出警告）")
+	exportCmd.Flags().BoolVar(&config.Strict, "strict", false, "严格校验：词典中格式错误的行写入 rejected.txt，字根编码非法时报错")
+	exportCmd.Flags().BoolVar(&config.IncludeASCII, "include-ascii", false, "保留纯 ASCII 字词（默认跳过）")
+	exportCmd.Flags().BoolVar(&config.NormalizeWidth, "normalize-width", false, "将字词中的全角字母、数字和标点转换为半角（不影响编码）")
+	exportCmd.Flags().StringVar(&config.UnicodeNormalize, "unicode-normalize", "none", "字词和字根的 Unicode 规范化形式：none、nfc、nfd（不影响编码）")
+	exportCmd.Flags().StringVar(&config.SplitBy, "split-by", "grapheme", "区分单字与词组的计数方式：grapheme（按字形簇）、rune（按码点）")
+	exportCmd.Flags().StringVar(&config.DictSplit, "split", "whitespace", "词典行的字段分隔方式：whitespace（任意空白）、tab（只按制表符分隔，保留字词中的空格；生成模板时也按分隔符严格读取导出文件）")
+	exportCmd.Flags().IntVar(&config.QuickWordCol, "quick-word-col", 0, "简码词典中字词所在的列（从 1 开始）；设置后允许额外的列，列数不足的行跳过并警告（0 表示按词典头的 columns）")
+	exportCmd.Flags().IntVar(&config.QuickCodeCol, "quick-code-col", 0, "简码词典中编码所在的列（从 1 开始，设置了 --quick-word-col 时默认为 2）")
+	exportCmd.Flags().IntVar(&config.PopWordCol, "pop-word-col", 0, "顶功词典中字词所在的列（从 1 开始），同 --quick-word-col")
+	exportCmd.Flags().IntVar(&config.PopCodeCol, "pop-code-col", 0, "顶功词典中编码所在的列（从 1 开始），同 --quick-code-col")
+	exportCmd.Flags().StringVar(&config.CodeCase, "code-case", "lower", "字根、简码、顶功编码的大小写：lower、upper、preserve（保持原样）")
+	exportCmd.Flags().StringVar(&config.CodeChars, "code-chars", "", "编码中除英文字母外允许的字符，支持范围写法（如 ;/ 或 0-9）")
+	exportCmd.Flags().StringVar(&config.StripToneMarks, "strip-tone-marks", "", "解析简码/顶功编码时先去掉其中的声调数字（如 ni3hao3 变为 nihao），值为声调字符集，支持范围写法（不带值时为 1-5）")
+	exportCmd.Flags().Lookup("strip-tone-marks").NoOptDefVal = "1-5"
+	exportCmd.Flags().IntVar(&config.MinCodeLen, "min-code-len", 0, "简码/顶功编码的最小长度（0 表示不限制）")
+	exportCmd.Flags().IntVar(&config.MaxCodeLen, "max-code-len", 0, "简码/顶功编码的最大长度（0 表示不限制）")
+	exportCmd.Flags().IntVar(&config.MaxKeyLen, "max-key-len", 0, "输入法的最大码长，简码/顶功编码超过时视为源文件错误：跳过并警告，--strict 时报错（0 表示不检查）")
+	exportCmd.Flags().IntVar(&config.MaxEntries, "max-entries", 0, "每个字根、简码、顶功输出文件最多写入的条目数（排序后取前 N 条，用于生成小样本；0 表示不限制）")
+	exportCmd.Flags().StringVar(&config.IgnoreFile, "ignore-file", "", "忽略列表文件：每行为“编码”（忽略该编码的所有条目）或“编码\t字词”（只忽略该条目），作用于字根、简码和顶功")
+	exportCmd.Flags().StringVar(&config.CodeMap, "code-map", "", "编码替换表文件：每行为“原\t新”，原为单个字符时替换编码中所有该字符，写作 ^前缀 时替换编码开头的前缀；在校验之后作用于字根、简码和顶功")
+	exportCmd.Flags().StringSliceVar(&config.Only, "only", nil, "只执行这些导出步骤（逗号分隔）：root、quick、pop、template")
+	exportCmd.Flags().StringSliceVar(&config.Skip, "skip", nil, "跳过这些导出步骤（逗号分隔）：root、quick、pop、template")
+	exportCmd.Flags().BoolVar(&watch, "watch", false, "监视源目录中的词典和模板文件，变化时自动重新导出（仅支持目录源，不能与 --update 同时使用）")
+
+	var extractSource string
+	var extractTarget string
+	var extractAllowSymlinks bool
+
+	var extractCmd = &cobra.Command{
+		Use:   "extract",
+		Short: "解压宇浩发布的 zip 文件并输出方案名",
+		Run: func(cmd *cobra.Command, args []string) {
+			schemaName, err := yuexport.Extract(cmd.Context(), extractSource, extractTarget, yuexport.ExtractOptions{AllowSymlinks: extractAllowSymlinks, Quiet: quiet})
+			checkErr(err)
+			fmt.Println(schemaName)
+		},
+	}
+
+	extractCmd.Flags().StringVarP(&extractSource, "source", "s", "", "宇浩发布的 zip 文件路径")
+	_ = extractCmd.MarkFlagRequired("source")
+	extractCmd.Flags().StringVarP(&extractTarget, "target", "t", "", "解压路径")
+	_ = extractCmd.MarkFlagRequired("target")
+	extractCmd.Flags().BoolVar(&extractAllowSymlinks, "allow-symlinks", false, "还原 zip 中的符号链接（目标须位于解压路径内，否则报错）；默认跳过并警告")
+
+	var diffSeparator string
+
+	var diffCmd = &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "比较两次导出结果（目录或 zip）的差异",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkErr(yuexport.DiffExports(args[0], args[1], yuexport.UnescapeSeparator(diffSeparator), os.Stdout))
+		},
+	}
+
+	diffCmd.Flags().StringVar(&diffSeparator, "separator", `\t`, "导出文件中编码与字词之间的分隔符（支持 \\t 等转义）")
+
+	var statsSeparator string
+	var statsAsJSON bool
+
+	var statsCmd = &cobra.Command{
+		Use:   "stats <dir>",
+		Short: "统计已导出目录（或 zip）中各文本文件的条目数、码长分布和重码情况",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			report, err := yuexport.AnalyzeExport(args[0], yuexport.UnescapeSeparator(statsSeparator))
+			checkErr(err)
+			checkErr(printExportReport(os.Stdout, report, statsAsJSON))
+		},
+	}
+
+	statsCmd.Flags().StringVar(&statsSeparator, "separator", `\t`, "导出文件中编码与字词之间的分隔符（支持 \\t 等转义）")
+	statsCmd.Flags().BoolVar(&statsAsJSON, "json", false, "以 JSON 格式输出统计")
+
+	var mergeOutput string
+	var mergeSeparator string
+	var mergeMultiWord bool
+
+	var mergeCmd = &cobra.Command{
+		Use:   "merge <file>...",
+		Short: "合并多个导出的文本文件（如 quick_words.txt），按编码排序去重",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stats, err := yuexport.MergeFiles(args, mergeOutput, yuexport.UnescapeSeparator(mergeSeparator), mergeMultiWord)
+			checkErr(err)
+			if !quiet {
+				fmt.Println(msg("files-merged", len(args), stats.File, stats.Entries))
+			}
+		},
+	}
+
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "", "合并结果的输出路径")
+	_ = mergeCmd.MarkFlagRequired("output")
+	mergeCmd.Flags().StringVar(&mergeSeparator, "separator", `\t`, "文件中编码与字词之间的分隔符（支持 \\t 等转义）")
+	mergeCmd.Flags().BoolVar(&mergeMultiWord, "multi-word", false, "同一编码的多个字词合并到一行，而不是只保留先出现的一个")
 
 	cmd.AddCommand(exportCmd)
+	cmd.AddCommand(extractCmd)
+	cmd.AddCommand(diffCmd)
+	cmd.AddCommand(mergeCmd)
+	cmd.AddCommand(statsCmd)
 
-	if err := cmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	locale = detectLocale(os.Args[1:])
+	localize(cmd)
+	// Flag errors stop parsing before a later --error-format is read, so it is scanned up front like --locale
+	if value, found := flagArg(os.Args[1:], "error-format"); found {
+		errorFormat = value
+	}
+
+	// Ctrl-C cancels the running command, which removes its temp files before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	checkErr(cmd.ExecuteContext(ctx))
+}
+
+// printError writes err to w as an "Error: ..." line, or with format json as an object
+// holding the message and the export stage and source file it came from, empty when unknown
+func printError(w io.Writer, err error, format string) {
+	if format != "json" {
+		fmt.Fprintln(w, "Error:", err)
+		return
+	}
+	out := struct {
+		Error string `json:"error"`
+		Stage string `json:"stage"`
+		File  string `json:"file"`
+	}{Error: err.Error()}
+	var stageErr *yuexport.StageError
+	if errors.As(err, &stageErr) {
+		out.Stage = stageErr.Stage
+	}
+	var fileErr *yuexport.FileError
+	if errors.As(err, &fileErr) {
+		out.File = fileErr.File
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// exportSchemas runs exportOne for each schema
+// Failures do not stop the remaining schemas; a summary is printed and all errors are returned together
+func exportSchemas(ctx context.Context, schemas []string, config yuexport.ExportConfig, quiet bool, exportOne func(context.Context, yuexport.ExportConfig) error) error {
+	var succeeded, failed []string
+	var errs []error
+	for _, schema := range schemas {
+		if ctx.Err() != nil {
+			break
+		}
+		schemaConfig := config
+		schemaConfig.Schema = schema
+		if err := exportOne(ctx, schemaConfig); err != nil {
+			failed = append(failed, schema)
+			errs = append(errs, msgErr("schema-failed", schema, err))
+			continue
+		}
+		succeeded = append(succeeded, schema)
+	}
+
+	if !quiet {
+		fmt.Print(msg("schemas-exported", len(succeeded), len(schemas)))
+		if len(succeeded) > 0 {
+			fmt.Print(msg("schemas-succeeded", strings.Join(succeeded, ", ")))
+		}
+		if len(failed) > 0 {
+			fmt.Print(msg("schemas-failed", strings.Join(failed, ", ")))
+		}
+		fmt.Println()
+	}
+	return errors.Join(errs...)
+}
+
+// setupLogger installs the default slog logger writing to stderr
+// Only errors are logged by default so scripted runs stay quiet
+// With a non-nil collector every warning is also recorded in it, whatever the level
+func setupLogger(verbose bool, logLevel string, collector *warningLog) error {
+	var level slog.Level
+	switch strings.ToLower(logLevel) {
+	case "debug":
+		level = slog.LevelDebug
+	case "info":
+		level = slog.LevelInfo
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		return fmt.Errorf("invalid log level: %s", logLevel)
+	}
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	if collector != nil {
+		handler = &warningHandler{Handler: handler, log: collector}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// printExportReport prints an export directory report as a table, or as JSON
+func printExportReport(w io.Writer, report yuexport.ExportReport, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tENTRIES\tCODES\tMULTI-WORD CODES\tLONGEST CODE\tMOST COLLIDED\tCODE LENGTHS")
+	for _, file := range report.Files {
+		lengths := make([]int, 0, len(file.CodeLengths))
+		for length := range file.CodeLengths {
+			lengths = append(lengths, length)
+		}
+		sort.Ints(lengths)
+		var dist []string
+		for _, length := range lengths {
+			dist = append(dist, fmt.Sprintf("%d:%d", length, file.CodeLengths[length]))
+		}
+		collided := ""
+		if file.MostCollidedWords > 1 {
+			collided = fmt.Sprintf("%s (%d)", file.MostCollidedCode, file.MostCollidedWords)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", file.File, file.Entries, file.Codes, file.MultiWordCodes,
+			file.LongestCode, collided, strings.Join(dist, " "))
+	}
+	fmt.Fprintf(tw, "total\t%d\n", report.Entries)
+	return tw.Flush()
+}
+
+// printExportStats prints per-file entry counts as a table, or as JSON
+func printExportStats(w io.Writer, stats yuexport.ExportStats, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	if stats.Method != "" {
+		fmt.Fprintf(w, "schema: %s, method: %s", stats.Schema, stats.Method)
+		if stats.Version != "" {
+			fmt.Fprintf(w, ", version: %s", stats.Version)
+		}
+		fmt.Fprintln(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tENTRIES")
+	unchanged := make(map[string]bool, len(stats.Unchanged))
+	for _, path := range stats.Unchanged {
+		unchanged[path] = true
+	}
+	for _, file := range stats.Files {
+		name := filepath.Base(file.File)
+		if unchanged[file.File] {
+			name += " (unchanged)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", name, file.Entries)
+	}
+	for _, tmpl := range stats.Templates {
+		for i, count := range tmpl.Items {
+			fmt.Fprintf(tw, "%s items[%d]\t%d\n", filepath.Base(tmpl.File), i, count)
+		}
+	}
+	for _, source := range stats.Sources {
+		if source.Skipped > 0 {
+			fmt.Fprintf(tw, "%s (skipped)\t%d\n", filepath.Base(source.File), source.Skipped)
+		}
 	}
+	return tw.Flush()
 }
@@ -0,0 +1,55 @@
+package yuexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// bundleEntry is one code-word pair in a --bundle file
+type bundleEntry struct {
+	Code string `json:"code"`
+	Word string `json:"word"`
+}
+
+// bundleKey names the entries of an output file in a bundle: its category, with the suffix appended if any
+func bundleKey(file FileStats) string {
+	if file.Suffix == "" {
+		return file.Category
+	}
+	return file.Category + "_" + file.Suffix
+}
+
+// writeBundle writes the entries of every exported text file to one JSON object keyed by bundleKey,
+// e.g. {"roots": [...], "quick_words": [...], "quick_words_tc": [...]}
+func writeBundle(path string, files []FileStats, config ExportConfig) error {
+	bundle := make(map[string][]bundleEntry, len(files))
+	for _, file := range files {
+		entries := make([]bundleEntry, 0, len(file.written))
+		for _, entry := range file.written {
+			entries = append(entries, bundleEntry{Code: entry[0], Word: entry[1]})
+		}
+		bundle[bundleKey(file)] = entries
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), config); err != nil {
+		return err
+	}
+	slog.Info("wrote bundle", "file", path, "outputs", len(files))
+	return nil
+}
+
+// removeTextOutputs deletes the text files of an export once --bundle-only has bundled their entries
+func removeTextOutputs(files []FileStats) error {
+	for _, file := range files {
+		if err := os.Remove(file.File); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove '%s': %w", file.File, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package yuexport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumsFile is the manifest a release may carry at its root, in sha256sum format
+const checksumsFile = "CHECKSUMS.txt"
+
+// readChecksums reads "<sha256>  <path>" lines into a map from slash-separated path to lower-case digest
+// A "*" before the path, as written by sha256sum in binary mode, is ignored; blank lines and "#" comments are skipped
+func readChecksums(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checksum manifest '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(trimLine(scanner.Text(), lineNum == 0))
+		lineNum++
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, found := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if !found || len(sum) != 64 || name == "" {
+			return nil, fmt.Errorf("invalid checksum line %d in '%s'", lineNum, path)
+		}
+		sums[strings.TrimPrefix(filepath.ToSlash(name), "./")] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading checksum manifest '%s': %w", path, err)
+	}
+	return sums, nil
+}
+
+// verifyChecksums checks every file under schemaDir against the CHECKSUMS.txt at root
+// Files whose digest differs or that the manifest does not list are all reported together
+func verifyChecksums(root, schemaDir string) error {
+	sums, err := readChecksums(filepath.Join(root, checksumsFile))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	err = filepath.WalkDir(schemaDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		expected, listed := sums[rel]
+		if !listed {
+			errs = append(errs, fmt.Errorf("%s: not in %s", rel, checksumsFile))
+			return nil
+		}
+		actual, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			errs = append(errs, fmt.Errorf("%s: checksum mismatch: expected %s, got %s", rel, expected, actual))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify checksums: %w", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("checksum verification failed:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
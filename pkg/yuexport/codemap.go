@@ -0,0 +1,83 @@
+package yuexport
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeMap holds the substitutions of a --code-map file
+// A nil *codeMap leaves codes unchanged
+type codeMap struct {
+	chars    map[rune]string
+	prefixes [][2]string // longest first
+}
+
+// readCodeMap reads "from<tab>to" lines; blank lines and "#" comments are skipped
+// A from of one character is replaced wherever it occurs in a code; a from written as "^prefix"
+// replaces that prefix at the start of a code. from is normalized by config.CodeCase, to is kept as is
+func readCodeMap(path string, config ExportConfig) (*codeMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open code map '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	m := &codeMap{chars: make(map[rune]string)}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(trimLine(scanner.Text(), lineNum == 0))
+		lineNum++
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from, to, found := strings.Cut(line, "\t")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !found || from == "" {
+			return nil, fmt.Errorf("invalid code map line %d in '%s': expected \"from<tab>to\"", lineNum, path)
+		}
+		if prefix, ok := strings.CutPrefix(from, "^"); ok && prefix != "" {
+			m.prefixes = append(m.prefixes, [2]string{normalizeCodeCase(prefix, config.CodeCase), to})
+			continue
+		}
+		from = normalizeCodeCase(from, config.CodeCase)
+		if utf8.RuneCountInString(from) != 1 {
+			return nil, fmt.Errorf("invalid code map line %d in '%s': '%s' is neither one character nor a ^prefix", lineNum, path, from)
+		}
+		r, _ := utf8.DecodeRuneInString(from)
+		m.chars[r] = to
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading code map '%s': %w", path, err)
+	}
+	sort.SliceStable(m.prefixes, func(i, j int) bool { return len(m.prefixes[i][0]) > len(m.prefixes[j][0]) })
+	return m, nil
+}
+
+// apply maps code: the longest matching prefix rule replaces the start of the code,
+// then the character rules replace the characters of the rest
+func (m *codeMap) apply(code string) string {
+	if m == nil {
+		return code
+	}
+	var mapped strings.Builder
+	for _, rule := range m.prefixes {
+		if rest, ok := strings.CutPrefix(code, rule[0]); ok {
+			mapped.WriteString(rule[1])
+			code = rest
+			break
+		}
+	}
+	for _, r := range code {
+		if to, ok := m.chars[r]; ok {
+			mapped.WriteString(to)
+		} else {
+			mapped.WriteRune(r)
+		}
+	}
+	return mapped.String()
+}
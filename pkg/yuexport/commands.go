@@ -0,0 +1,66 @@
+package yuexport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// defaultCommands are the key binding commands used by the bundled templates
+var defaultCommands = []string{"content_reload", "content_next"}
+
+// readCommandsFile reads one known command per line; blank lines and "#" comments are skipped
+func readCommandsFile(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open commands file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	commands := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	isFirstLine := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(trimLine(scanner.Text(), isFirstLine))
+		isFirstLine = false
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading commands file '%s': %w", path, err)
+	}
+	return commands, nil
+}
+
+// checkKeyBindings warns about key bindings whose command is not in config.commands, or defaultCommands when unset
+// With config.Strict it returns an error listing them instead
+func checkKeyBindings(bindings []KeyBinding, templatePath string, config ExportConfig) error {
+	known := config.commands
+	if known == nil {
+		known = make(map[string]bool)
+		for _, command := range defaultCommands {
+			known[command] = true
+		}
+	}
+
+	var errs []error
+	for i, binding := range bindings {
+		if known[binding.Command] {
+			continue
+		}
+		if config.Strict {
+			errs = append(errs, fmt.Errorf("key_bindings[%d]: key '%s' has unknown command '%s'", i, binding.Key, binding.Command))
+		} else {
+			slog.Warn("unknown key binding command", "template", templatePath, "key", binding.Key, "command", binding.Command)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid template '%s':\n%w", templatePath, errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package yuexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiffExports compares the text outputs of two export runs and prints added, removed and changed codes
+// oldPath and newPath may be target directories or zip files of them, sep is the separator used by both runs
+func DiffExports(oldPath, newPath, sep string, w io.Writer) error {
+	oldDir, cleanupOld, err := openExportDir(oldPath)
+	if err != nil {
+		return err
+	}
+	defer cleanupOld()
+
+	newDir, cleanupNew, err := openExportDir(newPath)
+	if err != nil {
+		return err
+	}
+	defer cleanupNew()
+
+	oldFiles, err := listOutputFiles(oldDir)
+	if err != nil {
+		return err
+	}
+	newFiles, err := listOutputFiles(newDir)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for name := range oldFiles {
+		names[name] = true
+	}
+	for name := range newFiles {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		oldFile, inOld := oldFiles[name]
+		newFile, inNew := newFiles[name]
+		switch {
+		case !inOld:
+			fmt.Fprintf(w, "== %s (only in new) ==\n", name)
+			continue
+		case !inNew:
+			fmt.Fprintf(w, "== %s (only in old) ==\n", name)
+			continue
+		}
+
+		category, _, _ := parseOutputFileName(name)
+		isRoots := category == "roots" || category == "roots_pinyin"
+		oldEntries, err := readCodeWordFile(oldFile, isRoots, sep, false)
+		if err != nil {
+			return err
+		}
+		newEntries, err := readCodeWordFile(newFile, isRoots, sep, false)
+		if err != nil {
+			return err
+		}
+		writeEntriesDiff(w, name, groupWordsByCode(oldEntries), groupWordsByCode(newEntries))
+	}
+	return nil
+}
+
+// writeEntriesDiff prints the differences of one output file, nothing if both sides are equal
+func writeEntriesDiff(w io.Writer, name string, oldCodes, newCodes map[string][]string) {
+	var added, removed, changed []string
+	for code, words := range newCodes {
+		oldWords, ok := oldCodes[code]
+		if !ok {
+			added = append(added, code)
+		} else if strings.Join(oldWords, " ") != strings.Join(words, " ") {
+			changed = append(changed, code)
+		}
+	}
+	for code := range oldCodes {
+		if _, ok := newCodes[code]; !ok {
+			removed = append(removed, code)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return
+	}
+
+	sortCodes(added)
+	sortCodes(removed)
+	sortCodes(changed)
+
+	fmt.Fprintf(w, "== %s: +%d -%d ~%d ==\n", name, len(added), len(removed), len(changed))
+	for _, code := range added {
+		fmt.Fprintf(w, "+ %s\t%s\n", code, strings.Join(newCodes[code], " "))
+	}
+	for _, code := range removed {
+		fmt.Fprintf(w, "- %s\t%s\n", code, strings.Join(oldCodes[code], " "))
+	}
+	for _, code := range changed {
+		fmt.Fprintf(w, "~ %s\t%s -> %s\n", code, strings.Join(oldCodes[code], " "), strings.Join(newCodes[code], " "))
+	}
+}
+
+// groupWordsByCode maps each code to its sorted, de-duplicated word set
+func groupWordsByCode(entries []DictEntry) map[string][]string {
+	seen := make(map[DictEntry]bool)
+	codes := make(map[string][]string)
+	for _, entry := range entries {
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		codes[entry[0]] = append(codes[entry[0]], entry[1])
+	}
+	for _, words := range codes {
+		sort.Strings(words)
+	}
+	return codes
+}
+
+// sortCodes sorts codes the same way sortByCode orders entries
+func sortCodes(codes []string) {
+	sort.Slice(codes, func(i, j int) bool {
+		if len(codes[i]) != len(codes[j]) {
+			return len(codes[i]) < len(codes[j])
+		}
+		return codes[i] < codes[j]
+	})
+}
+
+// openExportDir returns a directory holding export outputs, extracting zip files to a temp directory
+func openExportDir(path string) (string, func(), error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".zip") {
+		return path, func() {}, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "yu_tool_diff_")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	if err := extractZipToDir(context.Background(), path, tempDir, ExtractOptions{Quiet: true}); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to extract zip file '%s': %w", path, err)
+	}
+	return tempDir, cleanup, nil
+}
+
+// listOutputFiles returns recognized export output files in dir, keyed by file name
+func listOutputFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %w", dir, err)
+	}
+
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, _, ok := parseOutputFileName(entry.Name()); ok {
+			files[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,190 @@
+package yuexport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxDownloadSize caps the size of a downloaded source zip
+const maxDownloadSize = 1 << 30
+
+// isURLSource reports whether src is an http:// or https:// URL
+func isURLSource(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// stdinSource is the source name that reads the zip from standard input
+const stdinSource = "-"
+
+// isDirSource reports whether src is a local directory, e.g. an extracted release
+func isDirSource(src string) bool {
+	if isURLSource(src) || src == stdinSource {
+		return false
+	}
+	info, err := os.Stat(src)
+	return err == nil && info.IsDir()
+}
+
+// sourceFileName returns the file name part of a source path or URL
+func sourceFileName(src string) string {
+	if isURLSource(src) {
+		if u, err := url.Parse(src); err == nil {
+			return path.Base(u.Path)
+		}
+	}
+	return src
+}
+
+// downloadSource downloads a source zip to a temp file in tempDir, or the system temp directory when empty,
+// and returns its path. The caller is responsible for removing the file
+func downloadSource(ctx context.Context, src string, timeout time.Duration, tempDir string) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid source URL '%s': %w", src, err)
+	}
+
+	slog.Debug("downloading source", "url", src)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download '%s': %w", src, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download '%s': %s", src, resp.Status)
+	}
+	if err := checkZipContentType(resp.Header.Get("Content-Type")); err != nil {
+		return "", fmt.Errorf("failed to download '%s': %w", src, err)
+	}
+	if resp.ContentLength > maxDownloadSize {
+		return "", fmt.Errorf("failed to download '%s': size %d exceeds limit %d", src, resp.ContentLength, maxDownloadSize)
+	}
+
+	file, err := os.CreateTemp(tempDir, "yu_tool_*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	written, err := io.Copy(file, io.LimitReader(resp.Body, maxDownloadSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > maxDownloadSize {
+		err = fmt.Errorf("size exceeds limit %d", maxDownloadSize)
+	}
+	if err == nil && resp.ContentLength >= 0 && written != resp.ContentLength {
+		err = fmt.Errorf("got %d bytes, expected %d", written, resp.ContentLength)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to download '%s': %w", src, err)
+	}
+
+	slog.Debug("downloaded source", "url", src, "file", file.Name(), "bytes", written)
+	return file.Name(), nil
+}
+
+// checkZipContentType rejects responses that are clearly not zip files, such as HTML error pages
+func checkZipContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Type '%s': %w", contentType, err)
+	}
+	switch mediaType {
+	case "application/zip", "application/x-zip-compressed", "application/x-zip", "application/octet-stream":
+		return nil
+	}
+	return fmt.Errorf("unexpected Content-Type '%s'", mediaType)
+}
+
+// checkTempDir fails unless dir is an existing directory that temp files can be created in
+func checkTempDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("temp directory '%s' does not exist", dir)
+	}
+	file, err := os.CreateTemp(dir, ".yu_tool_check_*")
+	if err != nil {
+		return fmt.Errorf("temp directory '%s' is not writable: %w", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return nil
+}
+
+// readStdinSource copies a zip from standard input to a temp file in tempDir, since archive/zip needs random access
+// The caller is responsible for removing the file
+func readStdinSource(tempDir string) (string, error) {
+	file, err := os.CreateTemp(tempDir, "yu_tool_*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	written, err := io.Copy(file, io.LimitReader(os.Stdin, maxDownloadSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > maxDownloadSize {
+		err = fmt.Errorf("size exceeds limit %d", maxDownloadSize)
+	}
+	if err == nil && written == 0 {
+		err = errors.New("no data")
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to read source zip from stdin: %w", err)
+	}
+
+	slog.Debug("read source from stdin", "file", file.Name(), "bytes", written)
+	return file.Name(), nil
+}
+
+// fileSHA256 returns the hex SHA-256 digest of the file at path
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash '%s': %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifySHA256 hashes the file at path and compares it to the expected hex digest
+func verifySHA256(path, expected string) error {
+	actual, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("sha256 mismatch for '%s': expected %s, got %s", path, expected, actual)
+	}
+	slog.Debug("verified sha256", "file", path, "sha256", actual)
+	return nil
+}
@@ -0,0 +1,23 @@
+package yuexport
+
+// StageError marks an export error with the stage it came from: root, quick, pop or template
+// Its message is that of the wrapped error
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Err.Error() }
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// FileError marks an error with the source file that was being processed, such as a dict or template
+// Its message is that of the wrapped error
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string { return e.Err.Error() }
+
+func (e *FileError) Unwrap() error { return e.Err }
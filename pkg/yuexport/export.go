@@ -0,0 +1,2958 @@
+// Package yuexport exports the roots, quick words and templates of Yuhao input method releases
+package yuexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateMeta represents the full structure of a template.toml file (includes ItemsMeta for generation)
+type TemplateMeta struct {
+	Name          string              `toml:"name"`
+	Version       string              `toml:"version"`
+	ConfigVersion string              `toml:"config_version"`
+	Fonts         []TemplateFont      `toml:"fonts"`
+	KeyBindings   []KeyBinding        `toml:"key_bindings"`
+	ItemsMeta     []TemplateItemsMeta `toml:"items_meta"`
+	Tabs          []TemplateTab       `toml:"tabs"`
+	Text          []TemplateText      `toml:"text"`
+	Help          []string            `toml:"help"`
+}
+
+// Template represents the structure for export (same as TemplateMeta but without ItemsMeta)
+// Items holds the words ([]string) or, with --with-hints, the candidates ([]ItemCandidate) per code of each table,
+// see orderedItems
+type Template struct {
+	Name          string         `toml:"name" yaml:"name"`
+	Version       string         `toml:"version" yaml:"version"`
+	ConfigVersion string         `toml:"config_version" yaml:"config_version"`
+	Fonts         []TemplateFont `toml:"fonts" yaml:"fonts"`
+	KeyBindings   []KeyBinding   `toml:"key_bindings" yaml:"key_bindings"`
+	Items         any            `toml:"items" yaml:"items"`
+	Tabs          []TemplateTab  `toml:"tabs" yaml:"tabs"`
+	Text          []TemplateText `toml:"text" yaml:"text"`
+	Help          []string       `toml:"help" yaml:"help"`
+}
+
+// ItemCandidate is a word of a template items table together with its hint, e.g. the pinyin of a root
+type ItemCandidate struct {
+	Word string `toml:"word" yaml:"word"`
+	Hint string `toml:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+// DictEntry represents a code-word pair [code, word, weight]
+// weight is optional and empty when the source has no weight column
+type DictEntry [3]string
+
+// KeyBinding maps a key to a command in the template
+type KeyBinding struct {
+	Key     string `toml:"key" yaml:"key"`
+	Command string `toml:"command" yaml:"command"`
+}
+
+// TemplateText is a named text block in the template
+type TemplateText struct {
+	Name    string `toml:"name" yaml:"name"`
+	Content string `toml:"content" yaml:"content"`
+}
+
+// TemplateItemsMeta selects which exported entries become one items table of the template
+type TemplateItemsMeta struct {
+	Category      []string `toml:"category"`
+	Prefix        []string `toml:"prefix"`
+	Suffix        []string `toml:"suffix"`
+	ExcludePrefix []string `toml:"exclude_prefix"`
+	ExcludeSuffix []string `toml:"exclude_suffix"`
+	MinLength     int      `toml:"min_length"`
+	MaxLength     int      `toml:"max_length"`
+	CodeRegex     string   `toml:"code_regex"`
+	AppendPrefix  string   `toml:"append_prefix"`
+	AppendSuffix  string   `toml:"append_suffix"`
+	HintCategory  string   `toml:"hint_category"`
+}
+
+// TemplateFont is a font bundled with the template
+type TemplateFont struct {
+	Name   string `toml:"name" yaml:"name"`
+	File   string `toml:"file" yaml:"file"`
+	Type   string `toml:"type" yaml:"type"`
+	Base64 string `toml:"base64" yaml:"base64"`
+}
+
+// TemplateTab represents a tab in the template
+type TemplateTab struct {
+	Label string `toml:"label" yaml:"label"`
+	Group string `toml:"group" yaml:"group"`
+	Type  string `toml:"type" yaml:"type"`
+	Index []int  `toml:"index" yaml:"index"`
+}
+
+// ExportConfig contains configuration for export operations
+// The zero value of every option keeps the default behavior of the yu_tool CLI, except TargetPath and RootPath
+type ExportConfig struct {
+	MethodName string // dict file base name, derived from the schema by Export
+	Version    string // release version for template names; derived from the source name when empty
+	YuhaoPath  string // absolute YuhaoDir of the extracted source, set by Export
+	RootPath   string // roots CSV file, or a directory or glob of roots.csv and roots_<suffix>.csv files, see findRootFiles; required when the root stage runs
+	TargetPath string // output directory, created if missing
+
+	Schema            string   // schema to export from default.custom.yaml; the shortest name when empty
+	SchemaConfigFile  string   // schema list relative to the release root; schemaConfigFile when empty
+	YuhaoDir          string   // dict directory relative to the release root; defaultYuhaoDir when empty
+	MethodSuffixStrip []string // suffixes stripped from the schema name to get MethodName, e.g. "_exp"
+	Only              []string // stages to run (root, quick, pop, template); all when empty
+	Skip              []string // stages not to run
+	SHA256            string   // expected hex SHA-256 of a zip source, not checked when empty
+	VerifyChecksums   bool     // check the files of the schema directory against the CHECKSUMS.txt at the release root
+
+	TemplateDir    string // directory templates are read from; see templateSourceDir when empty
+	ItemsDir       string // directory template items are read from; TargetPath when empty
+	ItemsMerge     string // merge (default) or override, see generateItemsFromMeta
+	TemplateFormat string // toml (default) or yaml
+	Indent         string // template indentation: a number of spaces, "tab" or "0"; encoder default when empty
+	Update         bool   // write the bumped config_version back into the template files
+	MonotonicSeq   bool   // keep incrementing the config_version sequence across dates instead of restarting at 1
+	WithHints      bool   // write items as word/hint objects, hints read from each items_meta hint_category
+	ExtractHelp    bool   // write template help to a sibling name_help.html file and reference it from the template
+	CommandsFile   string // known key binding commands, one per line, replacing defaultCommands
+
+	NoOverwrite     bool   // fail before writing anything if an output file already exists
+	WriteRetries    int    // retries of creating and renaming an output file after a transient error
+	Append          bool   // merge the entries already in quick/pop output files with the new ones
+	Incremental     bool   // leave outputs whose content did not change untouched, keeping their modification time
+	PrevManifest    string // with Incremental, compare outputs against this sha256sum manifest of the previous export instead of the files on disk, then rewrite it
+	Clean           bool   // remove outputs of earlier runs from the target directory that this run does not write
+	TargetPerMethod bool   // write into TargetPath/<MethodName> so several methods can share one target
+	ZipOutput       string // also package TargetPath into this zip file
+	SQLitePath      string // also write all entries into this SQLite database
+	BundlePath      string // also write all root, quick and pop entries to this JSON file, keyed by category and suffix
+	BundleOnly      bool   // remove the text outputs once they are in the bundle
+	FreqReport      string // also write words-per-code and code length histograms of each output to this file, JSON if it ends with .json
+
+	Separator        string // between code and word in text outputs; a tab when empty
+	LineEnding       string // lf (default) or crlf, the line terminator of text outputs
+	NoFinalNewline   bool   // leave out the line ending after the last line of text outputs
+	CommentChar      string // prefix of comment lines in the roots CSV; "#" when empty
+	CSVDelimiter     string // field delimiter of the roots CSV, a single character; a comma when empty
+	ExportPinyin     bool   // also write roots_pinyin.txt from the third column of the roots CSV
+	SortBy           string // code (default), word or weight
+	RootSortBy       string // code (default) or word, the order of roots.txt; every root entry is kept either way
+	SortItemsBy      string // code (default), source or word, the order of codes in template items
+	PreserveOrder    bool   // keep source order instead of sorting
+	MultiWord        bool   // write all words of a code on one line instead of only the first
+	Combine          string // "also" or "only": write quick.txt/pop.txt with words and chars together, besides or instead of the split files
+	KeepGoing        bool   // run the remaining stages after a failure and report all errors at the end
+	FailOnEmpty      bool   // fail when an output file has no entries instead of logging a warning
+	Strict           bool   // collect malformed dict lines in rejected.txt and fail on invalid root codes
+	IncludeASCII     bool   // keep words made only of ASCII characters
+	NormalizeWidth   bool   // fold full-width letters, digits and punctuation in words to half-width
+	UnicodeNormalize string // none (default), nfc or nfd, the Unicode normalization form of words
+	SplitBy          string // grapheme (default) or rune, how word length separates chars from words
+	DictSplit        string // whitespace (default) or tab; tab splits dict lines on tabs only, keeping spaces in words
+	QuickWordCol     int    // 1-based word column of quick dict lines, allowing extra columns; 0 for the header's columns
+	QuickCodeCol     int    // 1-based code column of quick dict lines, see QuickWordCol
+	PopWordCol       int    // 1-based word column of pop dict lines, see QuickWordCol
+	PopCodeCol       int    // 1-based code column of pop dict lines, see QuickWordCol
+	CodeCase         string // lower (default), upper or preserve
+	CodeChars        string // characters allowed in codes besides a-z and A-Z, e.g. ";/" or "0-9"
+	StripToneMarks   string // tone-number characters removed from quick/pop codes before validation, e.g. "1-5"; ranges as in CodeChars
+	MinCodeLen       int    // drop quick/pop codes shorter than this, 0 for no limit
+	MaxCodeLen       int    // drop quick/pop codes longer than this, 0 for no limit
+	MaxKeyLen        int    // quick/pop codes longer than this are source errors: skipped with a warning, fatal with Strict
+	MaxEntries       int    // write at most this many entries, the first in sort order, to each root, quick and pop output; 0 for no limit
+	IgnoreFile       string // "code" or "code<tab>word" lines of root, quick and pop entries to drop
+	CodeMap          string // "from<tab>to" substitutions applied to root, quick and pop codes after validation, see readCodeMap
+	NameTemplate     string // text/template for output file names, see defaultNameTemplate
+
+	Timeout       time.Duration // download timeout for URL sources, 0 for none
+	KeepTemp      bool          // keep the extraction directory and print its path
+	Quiet         bool          // no progress output on stderr
+	AllowSymlinks bool          // recreate symlink entries of zip sources whose target stays inside the extraction directory; skipped with a warning otherwise
+	TempDir       string        // parent directory of downloaded zips and the extraction directory; the system temp directory when empty
+
+	rejected     *rejectedLines
+	sources      *sourceCounts
+	unchanged    *unchangedFiles
+	manifest     *outputManifest
+	ignore       *ignoreList
+	codeMap      *codeMap
+	commands     map[string]bool
+	schemaDir    string
+	extracted    bool // the source was extracted to a temp directory, so its templates are copies
+	codeChars    string
+	toneMarks    string
+	nameTemplate *template.Template
+	progress     *progress
+}
+
+// FileStats records how many entries were written to an output file
+type FileStats struct {
+	File     string `json:"file"`
+	Category string `json:"category"`
+	Suffix   string `json:"suffix,omitempty"`
+	Entries  int    `json:"entries"`
+
+	written []DictEntry
+	parsed  []DictEntry // entries read for the file before de-duplication by code, when they differ from written
+}
+
+// TemplateStats records how many codes each items entry of an exported template holds
+type TemplateStats struct {
+	File  string `json:"file"`
+	Items []int  `json:"items"`
+}
+
+// SourceStats records how many lines of an input file became entries and how many were skipped
+// Blank lines, comments and the dict header are not counted
+type SourceStats struct {
+	File    string `json:"file"`
+	Entries int    `json:"entries"`
+	Skipped int    `json:"skipped"`
+}
+
+// ExportStats summarizes the outputs of an export run
+type ExportStats struct {
+	Schema    string          `json:"schema"`
+	Method    string          `json:"method"`
+	Version   string          `json:"version,omitempty"`
+	TempDir   string          `json:"temp_dir,omitempty"`      // extraction directory left in place by KeepTemp
+	Others    []string        `json:"other_schemas,omitempty"` // independent schemas of the source not exported, when none was selected
+	Suffixes  []string        `json:"suffixes,omitempty"`
+	Sources   []SourceStats   `json:"sources"`
+	Unchanged []string        `json:"unchanged,omitempty"`
+	Files     []FileStats     `json:"files"`
+	Templates []TemplateStats `json:"templates"`
+}
+
+// Export exports roots, quick and pop words and templates from src into config.TargetPath
+// src is a release zip, an http(s) URL of one, "-" for a zip on stdin, or an extracted release directory
+// Cancelling ctx aborts the download, extraction and dict reading and removes the temp directory
+func Export(ctx context.Context, src string, config ExportConfig) (stats ExportStats, err error) {
+	if config.Separator == "" {
+		config.Separator = "\t"
+	}
+	if config.CommentChar == "" {
+		config.CommentChar = "#"
+	}
+	if config.SchemaConfigFile == "" {
+		config.SchemaConfigFile = schemaConfigFile
+	}
+	if config.YuhaoDir == "" {
+		config.YuhaoDir = defaultYuhaoDir
+	}
+	if config.CSVDelimiter == "" {
+		config.CSVDelimiter = ","
+	}
+
+	switch config.SortBy {
+	case "", "code", "word", "weight":
+	default:
+		return stats, fmt.Errorf("invalid sort order: %s (expected code, word or weight)", config.SortBy)
+	}
+	switch config.RootSortBy {
+	case "", "code", "word":
+	default:
+		return stats, fmt.Errorf("invalid root sort order: %s (expected code or word)", config.RootSortBy)
+	}
+	switch config.SplitBy {
+	case "", "grapheme", "rune":
+	default:
+		return stats, fmt.Errorf("invalid split mode: %s (expected grapheme or rune)", config.SplitBy)
+	}
+	switch config.CodeCase {
+	case "", "lower", "upper", "preserve":
+	default:
+		return stats, fmt.Errorf("invalid code case: %s (expected lower, upper or preserve)", config.CodeCase)
+	}
+	switch config.UnicodeNormalize {
+	case "", "none", "nfc", "nfd":
+	default:
+		return stats, fmt.Errorf("invalid unicode normalization: %s (expected none, nfc or nfd)", config.UnicodeNormalize)
+	}
+	switch config.SortItemsBy {
+	case "", "code", "source", "word":
+	default:
+		return stats, fmt.Errorf("invalid items sort order: %s (expected code, source or word)", config.SortItemsBy)
+	}
+	switch config.Combine {
+	case "", "also", "only":
+	default:
+		return stats, fmt.Errorf("invalid combine mode: %s (expected also or only)", config.Combine)
+	}
+	switch config.ItemsMerge {
+	case "", "merge", "override":
+	default:
+		return stats, fmt.Errorf("invalid items merge mode: %s (expected merge or override)", config.ItemsMerge)
+	}
+	switch config.TemplateFormat {
+	case "", "toml", "yaml":
+	default:
+		return stats, fmt.Errorf("invalid template format: %s (expected toml or yaml)", config.TemplateFormat)
+	}
+	if _, err := parseIndent(config.Indent, config.TemplateFormat); err != nil {
+		return stats, err
+	}
+	for _, fileType := range []string{"quick", "pop"} {
+		wordCol, codeCol := config.QuickWordCol, config.QuickCodeCol
+		if fileType == "pop" {
+			wordCol, codeCol = config.PopWordCol, config.PopCodeCol
+		}
+		if wordCol < 0 || codeCol < 0 {
+			return stats, fmt.Errorf("invalid %s columns: column numbers start at 1", fileType)
+		}
+		if word, code, explicit := dictColumnFlags(fileType, config); explicit && word == code {
+			return stats, fmt.Errorf("invalid %s columns: word and code are both column %d", fileType, word+1)
+		}
+	}
+	switch config.DictSplit {
+	case "", "whitespace", "tab":
+	default:
+		return stats, fmt.Errorf("invalid dict split: %s (expected whitespace or tab)", config.DictSplit)
+	}
+	switch config.LineEnding {
+	case "", "lf", "crlf":
+	default:
+		return stats, fmt.Errorf("invalid line ending: %s (expected lf or crlf)", config.LineEnding)
+	}
+	if config.codeChars, err = expandCodeChars(config.CodeChars); err != nil {
+		return stats, err
+	}
+	if config.toneMarks, err = expandCodeChars(config.StripToneMarks); err != nil {
+		return stats, fmt.Errorf("invalid tone marks: %w", err)
+	}
+	if delim, size := utf8.DecodeRuneInString(config.CSVDelimiter); size != len(config.CSVDelimiter) || strings.ContainsRune("\"\r\n", delim) || delim == utf8.RuneError {
+		return stats, fmt.Errorf("invalid CSV delimiter: %q (expected a single character other than a quote or newline)", config.CSVDelimiter)
+	}
+
+	for _, stage := range append(slices.Clone(config.Only), config.Skip...) {
+		if !slices.Contains(exportStages, stage) {
+			return stats, fmt.Errorf("invalid stage: %s (expected %s)", stage, strings.Join(exportStages, ", "))
+		}
+	}
+
+	if stageEnabled(config, "root") && config.RootPath == "" {
+		return stats, errors.New("--root is required to export roots (or exclude the root stage with --only/--skip)")
+	}
+	if stageEnabled(config, "root") {
+		if _, err := findRootFiles(config.RootPath); err != nil {
+			return stats, err
+		}
+	}
+	if info, err := os.Stat(config.TargetPath); err == nil && !info.IsDir() {
+		return stats, fmt.Errorf("target path '%s' is a file, expected a directory", config.TargetPath)
+	}
+
+	nameTemplate, err := parseNameTemplate(config.NameTemplate)
+	if err != nil {
+		return stats, err
+	}
+	if config.TempDir != "" {
+		if err := checkTempDir(config.TempDir); err != nil {
+			return stats, err
+		}
+	}
+	config.nameTemplate = nameTemplate
+
+	// Validate src is a zip file or an already extracted directory
+	sourceName := sourceFileName(src)
+	sourceIsDir := isDirSource(src)
+	if !sourceIsDir && src != stdinSource && !strings.HasSuffix(strings.ToLower(sourceName), ".zip") {
+		return stats, fmt.Errorf("source must be a zip file or directory, got: %s", src)
+	}
+
+	if sourceIsDir && config.SHA256 != "" {
+		return stats, fmt.Errorf("--sha256 requires a zip source, got directory: %s", src)
+	}
+
+	var schemaRoot, methodName string
+	if sourceIsDir {
+		schemaRoot, methodName, err = readSchemaRoot(src, config.SchemaConfigFile, config.Schema)
+		if err != nil {
+			return stats, err
+		}
+	} else {
+		// Buffer stdin to a temp file
+		if src == stdinSource {
+			localPath, err := readStdinSource(config.TempDir)
+			if err != nil {
+				return stats, err
+			}
+			defer os.Remove(localPath)
+			src = localPath
+		}
+
+		// Download URL sources to a temp file
+		if isURLSource(src) {
+			localPath, err := downloadSource(ctx, src, config.Timeout, config.TempDir)
+			if err != nil {
+				return stats, err
+			}
+			defer os.Remove(localPath)
+			src = localPath
+		}
+
+		if config.SHA256 != "" {
+			if err := verifySHA256(src, config.SHA256); err != nil {
+				return stats, err
+			}
+		}
+
+		// Extract zip to temporary directory
+		tempDir, err := os.MkdirTemp(config.TempDir, "yu_tool_")
+		if err != nil {
+			return stats, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		if config.KeepTemp {
+			stats.TempDir = tempDir
+			slog.Info("keeping extracted files", "dir", tempDir)
+		} else {
+			defer os.RemoveAll(tempDir)
+		}
+
+		schemaRoot, methodName, err = extractSchema(ctx, src, tempDir, config.SchemaConfigFile, config.Schema,
+			ExtractOptions{AllowSymlinks: config.AllowSymlinks, Quiet: config.Quiet})
+		if err != nil {
+			return stats, err
+		}
+		config.extracted = true
+	}
+
+	baseMethodName := parseMethodName(methodName, config.MethodSuffixStrip)
+	slog.Info("resolved schema name", "schema", methodName, "method", baseMethodName)
+
+	// Extract version from source filename unless given explicitly; stdin has no name, so only --version applies
+	// Format: methodName_version.zip or methodName_suffix_version.zip, or the same without .zip for directories
+	if config.Version == "" && sourceName != stdinSource {
+		config.Version = extractVersionFromFilename(sourceName)
+	}
+
+	config.MethodName = baseMethodName
+	if config.TargetPerMethod {
+		config.TargetPath = filepath.Join(config.TargetPath, baseMethodName)
+	}
+	stats.Schema, stats.Method, stats.Version = methodName, baseMethodName, config.Version
+	if config.Schema == "" {
+		if names, err := readSchemaNames(filepath.Join(schemaRoot, filepath.FromSlash(config.SchemaConfigFile))); err == nil {
+			stats.Others = otherSchemas(names, methodName)
+		}
+	}
+
+	// Sources, suffixes and unchanged outputs are filled in on every return, including failed runs
+	config.sources = &sourceCounts{}
+	config.unchanged = &unchangedFiles{}
+	defer func() {
+		stats.Sources = config.sources.stats
+		stats.Suffixes = outputSuffixes(stats.Files)
+		stats.Unchanged = config.unchanged.paths
+	}()
+	config.YuhaoPath = filepath.Join(schemaRoot, filepath.FromSlash(config.YuhaoDir))
+	config.schemaDir = filepath.Dir(filepath.Join(schemaRoot, filepath.FromSlash(config.SchemaConfigFile)))
+	if config.VerifyChecksums {
+		if err := verifyChecksums(schemaRoot, config.schemaDir); err != nil {
+			return stats, err
+		}
+	}
+	if info, err := os.Stat(config.YuhaoPath); err != nil || !info.IsDir() {
+		return stats, fmt.Errorf("dict directory '%s' not found in source (set --yuhao-path for other layouts)", config.YuhaoDir)
+	}
+
+	// Ensure target directory exists
+	if _, err := os.Stat(config.TargetPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(config.TargetPath, 0755); err != nil {
+			return stats, fmt.Errorf("failed to create target directory '%s': %w", config.TargetPath, err)
+		}
+	}
+
+	if config.TemplateDir != "" {
+		if info, err := os.Stat(config.TemplateDir); err != nil || !info.IsDir() {
+			return stats, fmt.Errorf("template directory '%s' does not exist", config.TemplateDir)
+		}
+	}
+
+	if config.ItemsDir != "" {
+		if info, err := os.Stat(config.ItemsDir); err != nil || !info.IsDir() {
+			return stats, fmt.Errorf("items directory '%s' does not exist", config.ItemsDir)
+		}
+	}
+
+	if config.BundleOnly && config.BundlePath == "" {
+		return stats, errors.New("--bundle-only requires --bundle")
+	}
+	if config.PrevManifest != "" {
+		if !config.Incremental {
+			return stats, errors.New("--prev-manifest requires --incremental")
+		}
+		if config.manifest, err = readOutputManifest(config.PrevManifest); err != nil {
+			return stats, err
+		}
+	}
+	if config.NoOverwrite && config.Append {
+		return stats, errors.New("--append needs to overwrite existing output files")
+	}
+	if config.NoOverwrite {
+		if err := checkOverwrite(config); err != nil {
+			return stats, err
+		}
+	}
+	if config.Clean {
+		if err := cleanOutputs(config); err != nil {
+			return stats, err
+		}
+	}
+
+	if outputs, err := plannedOutputs(config); err == nil {
+		config.progress = newProgress("Exporting", len(outputs), config.Quiet)
+		defer config.progress.Finish()
+	}
+
+	if config.Strict {
+		config.rejected = &rejectedLines{}
+	}
+
+	if config.IgnoreFile != "" {
+		if config.ignore, err = readIgnoreFile(config.IgnoreFile, config); err != nil {
+			return stats, err
+		}
+		defer config.ignore.logRemoved()
+	}
+	if config.CodeMap != "" {
+		if config.codeMap, err = readCodeMap(config.CodeMap, config); err != nil {
+			return stats, err
+		}
+	}
+	if config.CommandsFile != "" {
+		if config.commands, err = readCommandsFile(config.CommandsFile); err != nil {
+			return stats, err
+		}
+	}
+
+	// With KeepGoing, stage errors are collected and reported together at the end
+	var errs []error
+	handleErr := func(err error) error {
+		if !config.KeepGoing || ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, err)
+		return nil
+	}
+
+	// Export root
+	if stageEnabled(config, "root") {
+		rootStats, err := exportRoot(ctx, config)
+		stats.Files = append(stats.Files, rootStats...)
+		if err != nil {
+			if err := handleErr(&StageError{Stage: "root", Err: &FileError{File: config.RootPath, Err: fmt.Errorf("failed to export root: %w", err)}}); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	// Export quick words
+	if stageEnabled(config, "quick") {
+		quickStats, err := exportQuickWords(ctx, config)
+		stats.Files = append(stats.Files, quickStats...)
+		if err != nil {
+			if err := handleErr(&StageError{Stage: "quick", Err: fmt.Errorf("failed to export quick words: %w", err)}); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	// Export pop words (ignore if file doesn't exist)
+	if stageEnabled(config, "pop") {
+		popStats, err := exportPopWords(ctx, config)
+		stats.Files = append(stats.Files, popStats...)
+		if err != nil {
+			if !strings.Contains(err.Error(), "no such file or directory") &&
+				!strings.Contains(err.Error(), "cannot find the file") {
+				if err := handleErr(&StageError{Stage: "pop", Err: fmt.Errorf("failed to export pop words: %w", err)}); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+
+	// Export template file if exists
+	if stageEnabled(config, "template") {
+		templateStats, err := exportTemplate(config)
+		stats.Templates = append(stats.Templates, templateStats...)
+		if err != nil {
+			if err := handleErr(&StageError{Stage: "template", Err: fmt.Errorf("failed to export template: %w", err)}); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if config.Strict {
+		if err := writeRejected(config); err != nil {
+			if err := handleErr(err); err != nil {
+				return stats, err
+			}
+		}
+		config.progress.Add(1)
+	}
+
+	if err := checkEmptyOutputs(stats.Files, config.FailOnEmpty); err != nil {
+		if err := handleErr(err); err != nil {
+			return stats, err
+		}
+	}
+
+	if len(errs) > 0 {
+		return stats, errors.Join(errs...)
+	}
+
+	// Write the frequency report if requested
+	if config.FreqReport != "" {
+		slog.Debug("writing frequency report", "file", config.FreqReport)
+		if err := writeFreqReport(config.FreqReport, stats.Files, config); err != nil {
+			return stats, fmt.Errorf("failed to write frequency report: %w", err)
+		}
+		config.progress.Add(1)
+	}
+
+	// Write the JSON bundle if requested, replacing the text outputs with BundleOnly
+	if config.BundlePath != "" {
+		slog.Debug("writing bundle", "file", config.BundlePath)
+		if err := writeBundle(config.BundlePath, stats.Files, config); err != nil {
+			return stats, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		config.progress.Add(1)
+		if config.BundleOnly {
+			if err := removeTextOutputs(stats.Files); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	// Write SQLite database if requested
+	if config.SQLitePath != "" {
+		slog.Debug("writing sqlite database", "file", config.SQLitePath)
+		if err := writeSQLite(config.SQLitePath, stats.Files); err != nil {
+			return stats, fmt.Errorf("failed to write sqlite database: %w", err)
+		}
+		config.progress.Add(1)
+	}
+
+	// Replace the manifest with the digests of this export, so the next incremental run compares against it
+	if err := config.manifest.write(config); err != nil {
+		return stats, err
+	}
+
+	// Package target directory if requested
+	if config.ZipOutput != "" {
+		slog.Debug("packaging output", "dir", config.TargetPath, "zip", config.ZipOutput)
+		if err := zipDir(config.TargetPath, config.ZipOutput); err != nil {
+			return stats, fmt.Errorf("failed to create output zip: %w", err)
+		}
+		config.progress.Add(1)
+	}
+
+	return stats, nil
+}
+
+// outputSuffixes lists the distinct non-empty suffixes of the output files, sorted
+func outputSuffixes(files []FileStats) []string {
+	var suffixes []string
+	for _, file := range files {
+		if file.Suffix != "" && !slices.Contains(suffixes, file.Suffix) {
+			suffixes = append(suffixes, file.Suffix)
+		}
+	}
+	sort.Strings(suffixes)
+	return suffixes
+}
+
+// exportStages are the stage names accepted by --only and --skip, in the order they run
+var exportStages = []string{"root", "quick", "pop", "template"}
+
+// stageEnabled reports whether a stage runs: listed in Only (or Only is empty) and not listed in Skip
+func stageEnabled(config ExportConfig, stage string) bool {
+	if len(config.Only) > 0 && !slices.Contains(config.Only, stage) {
+		return false
+	}
+	return !slices.Contains(config.Skip, stage)
+}
+
+// ExtractOptions controls how release zips are extracted
+type ExtractOptions struct {
+	AllowSymlinks bool // recreate symlink entries whose target stays inside the destination, see extractZipToDir
+	Quiet         bool // no progress output on stderr
+}
+
+// Extract unzips src into destDir, leaving the files in place, and returns the schema name
+func Extract(ctx context.Context, src, destDir string, opts ExtractOptions) (string, error) {
+	if !strings.HasSuffix(strings.ToLower(src), ".zip") {
+		return "", fmt.Errorf("source must be a zip file, got: %s", src)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory '%s': %w", destDir, err)
+	}
+	_, methodName, err := extractSchema(ctx, src, destDir, schemaConfigFile, "", opts)
+	return methodName, err
+}
+
+// extractSchema extracts the zip at src into destDir and reads the schema name from schema/default.custom.yaml
+// The returned root is destDir, or its only subdirectory when the zip wraps everything in one
+func extractSchema(ctx context.Context, src, destDir, configFile, schema string, opts ExtractOptions) (root, methodName string, err error) {
+	slog.Debug("extracting zip", "source", src, "dir", destDir)
+	if err := extractZipToDir(ctx, src, destDir, opts); err != nil {
+		return "", "", fmt.Errorf("failed to extract zip file: %w", err)
+	}
+	return readSchemaRoot(destDir, configFile, schema)
+}
+
+// schemaConfigFile is where a release keeps its schema list, relative to the release root
+const schemaConfigFile = "schema/default.custom.yaml"
+
+// defaultYuhaoDir is where a release keeps its dict files, relative to the release root
+const defaultYuhaoDir = "schema/yuhao"
+
+// readSchemaRoot locates configFile (relative, e.g. schemaConfigFile) under dir and reads the schema name from it,
+// see readSchemaName
+// If dir has no configFile but exactly one subdirectory, that subdirectory is tried instead
+func readSchemaRoot(dir, configFile, schema string) (root, methodName string, err error) {
+	configFile = filepath.FromSlash(configFile)
+	root = dir
+	if _, err := os.Stat(filepath.Join(root, configFile)); os.IsNotExist(err) {
+		if sub, ok := singleSubdir(dir); ok {
+			if _, err := os.Stat(filepath.Join(sub, configFile)); err == nil {
+				slog.Debug("descending into top-level directory", "dir", sub)
+				root = sub
+			}
+		}
+	}
+
+	methodName, err = readSchemaName(filepath.Join(root, configFile), schema)
+	if os.IsNotExist(err) {
+		return "", "", fmt.Errorf("%s not found: expected it at the top level of the source, "+
+			"the zip may have an extra top-level directory", filepath.ToSlash(configFile))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read schema name: %w", err)
+	}
+	return root, methodName, nil
+}
+
+// singleSubdir returns the only entry of dir if it is a directory
+// Archive metadata added by macOS (__MACOSX, .DS_Store) is not counted
+func singleSubdir(dir string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	var only os.DirEntry
+	for _, entry := range entries {
+		if entry.Name() == "__MACOSX" || entry.Name() == ".DS_Store" {
+			continue
+		}
+		if only != nil {
+			return "", false
+		}
+		only = entry
+	}
+	if only == nil || !only.IsDir() {
+		return "", false
+	}
+	return filepath.Join(dir, only.Name()), true
+}
+
+// parseMethodName derives the base name used for dict files by stripping the first matching suffix,
+// e.g. "ziranma_exp" with suffix "_exp" becomes "ziranma"; a suffix equal to the whole name is kept
+func parseMethodName(methodName string, stripSuffixes []string) string {
+	for _, suffix := range stripSuffixes {
+		if base, ok := strings.CutSuffix(methodName, suffix); ok && suffix != "" && base != "" {
+			return base
+		}
+	}
+	return methodName
+}
+
+// extractVersionFromFilename extracts version from source filename
+// Format: methodName_version.zip or methodName_suffix_version.zip
+// Returns the last '_'-separated part that looks like a version (e.g. "3.2"),
+// falling back to the second part when none does
+func extractVersionFromFilename(filename string) string {
+	baseName := filepath.Base(filename)
+	// Remove .zip suffix
+	baseName = strings.TrimSuffix(baseName, ".zip")
+	// Split by '_'
+	parts := strings.Split(baseName, "_")
+	for i := len(parts) - 1; i >= 1; i-- {
+		if versionPattern.MatchString(parts[i]) {
+			return parts[i]
+		}
+	}
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+// versionPattern matches version parts of a filename like "3" or "3.9.0"
+var versionPattern = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// findRootFiles maps the suffix of each roots CSV to its path
+// rootPath is a single CSV file, exported without a suffix, or a directory or glob pattern of files named
+// roots.csv (no suffix) and roots_<suffix>.csv; other files in a directory are ignored, while a glob must only match such files
+func findRootFiles(rootPath string) (map[string]string, error) {
+	var paths []string
+	fromDir := false
+	if info, err := os.Stat(rootPath); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root directory '%s': %w", rootPath, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !isIgnoredFile(entry.Name()) {
+				paths = append(paths, filepath.Join(rootPath, entry.Name()))
+			}
+		}
+		fromDir = true
+	} else if strings.ContainsAny(rootPath, "*?[") {
+		matches, err := filepath.Glob(rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root pattern '%s': %w", rootPath, err)
+		}
+		paths = matches
+	} else {
+		return map[string]string{"": rootPath}, nil
+	}
+
+	files := make(map[string]string)
+	for _, path := range paths {
+		base, isCSV := strings.CutSuffix(filepath.Base(path), ".csv")
+		suffix, isRoots := strings.CutPrefix(base, "roots_")
+		if base == "roots" {
+			suffix, isRoots = "", true
+		}
+		if !isCSV || !isRoots || (base != "roots" && suffix == "") {
+			if fromDir {
+				slog.Debug("skipped file that is not a roots CSV", "file", path)
+				continue
+			}
+			return nil, fmt.Errorf("root pattern '%s' matched '%s', expected roots.csv or roots_<suffix>.csv", rootPath, path)
+		}
+		// roots_pinyin.txt and roots_pinyin_<suffix>.txt are the pinyin outputs
+		if suffix == "pinyin" || strings.HasPrefix(suffix, "pinyin_") {
+			return nil, fmt.Errorf("invalid roots file '%s': the suffix '%s' clashes with roots_pinyin outputs", path, suffix)
+		}
+		files[suffix] = path
+		slog.Debug("found roots file", "suffix", suffix, "file", path)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no roots.csv or roots_<suffix>.csv files found in '%s'", rootPath)
+	}
+	return files, nil
+}
+
+// findSuffixedFiles finds files matching pattern: methodName_*.fileType.dict.yaml, optionally gzipped (.gz)
+// Returns map of suffix -> file path; a plain file wins over a gzipped one with the same suffix
+func findSuffixedFiles(yuhaoPath, methodName, fileType string) map[string]string {
+	suffixes := make(map[string]string)
+
+	entries, err := os.ReadDir(yuhaoPath)
+	if err != nil {
+		return suffixes
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if isIgnoredFile(name) {
+			slog.Debug("skipped hidden or backup file", "file", name)
+			continue
+		}
+		base, compressed := strings.CutSuffix(name, ".gz")
+		if !strings.HasPrefix(base, methodName+"_") || !strings.HasSuffix(base, "."+fileType+".dict.yaml") {
+			continue
+		}
+		// Extract suffix: methodName_suffix.fileType.dict.yaml -> suffix
+		middle := strings.TrimPrefix(base, methodName+"_")
+		suffix := strings.TrimSuffix(middle, "."+fileType+".dict.yaml")
+		if _, found := suffixes[suffix]; found && compressed {
+			continue
+		}
+		if suffix != "" && middle != suffix {
+			suffixes[suffix] = filepath.Join(yuhaoPath, name)
+			slog.Debug("found suffixed file", "type", fileType, "suffix", suffix, "file", name)
+		}
+	}
+	return suffixes
+}
+
+// mainDictPath returns the path of the unsuffixed methodName.fileType.dict.yaml,
+// or of its gzipped variant when only that exists
+func mainDictPath(yuhaoPath, methodName, fileType string) string {
+	path := filepath.Join(yuhaoPath, methodName+"."+fileType+".dict.yaml")
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(path + ".gz"); err == nil {
+			return path + ".gz"
+		}
+	}
+	return path
+}
+
+// isIgnoredFile reports whether name is a hidden file, an editor lock file or a backup
+// e.g. ".#yuling.quick.dict.yaml", "yuling_tc.quick.dict.yaml~" or "yuling_tc.quick.dict.yaml.bak"
+func isIgnoredFile(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".bak")
+}
+
+// readSchemaName reads the schema name from default.custom.yaml
+// With schema set, that exact name is required to be listed; otherwise the shortest name is used
+func readSchemaName(configPath, schema string) (string, error) {
+	schemaNames, err := readSchemaNames(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	if schema != "" {
+		if slices.Contains(schemaNames, schema) {
+			return schema, nil
+		}
+		return "", fmt.Errorf("schema '%s' not found in default.custom.yaml (available: %s)", schema, strings.Join(schemaNames, ", "))
+	}
+
+	// Use the shortest schema name
+	shortest := schemaNames[0]
+	for _, name := range schemaNames[1:] {
+		if len(name) < len(shortest) {
+			shortest = name
+		}
+	}
+
+	return shortest, nil
+}
+
+// otherSchemas lists the schemas of names that are not exported along with schema
+// Variants like "yuling_tc" are exported with their base schema, so only the independent ones are listed
+func otherSchemas(names []string, schema string) []string {
+	var others []string
+	for _, name := range names {
+		if name != schema && !strings.HasPrefix(name, schema+"_") {
+			others = append(others, name)
+		}
+	}
+	return others
+}
+
+// readSchemaNames lists the schema names in default.custom.yaml
+// Looks for lines containing "- schema:" and extracts the schema name
+func readSchemaNames(configPath string) ([]string, error) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var schemaNames []string
+	scanner := bufio.NewScanner(file)
+	isFirstLine := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(trimLine(scanner.Text(), isFirstLine))
+		isFirstLine = false
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Find lines containing "- schema:"
+		if idx := strings.Index(line, "- schema:"); idx != -1 {
+			// Get the part after "- schema:"
+			schemaPart := strings.TrimSpace(line[idx+len("- schema:"):])
+			// Extract the first word (schema name)
+			fields := strings.Fields(schemaPart)
+			if len(fields) > 0 {
+				schemaNames = append(schemaNames, fields[0])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(schemaNames) == 0 {
+		return nil, errors.New("no schema name found in default.custom.yaml")
+	}
+	return schemaNames, nil
+}
+
+func sortByCode(entries []DictEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		codeI := entries[i][0]
+		codeJ := entries[j][0]
+		if len(codeI) != len(codeJ) {
+			return len(codeI) < len(codeJ)
+		}
+		return codeI < codeJ
+	})
+}
+
+// sortByWord sorts entries by word, then by code
+func sortByWord(entries []DictEntry) {
+	sortByCode(entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i][1] < entries[j][1]
+	})
+}
+
+// sortByWeight sorts entries by descending weight, keeping code order for equal weights
+// Entries without a weight go last; if no entry has a weight this is the same as sortByCode
+func sortByWeight(entries []DictEntry) {
+	sortByCode(entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		weightI, okI := parseWeight(entries[i][2])
+		weightJ, okJ := parseWeight(entries[j][2])
+		if okI != okJ {
+			return okI
+		}
+		return weightI > weightJ
+	})
+}
+
+// sortEntries sorts entries according to config.SortBy, or keeps source order with PreserveOrder
+func sortEntries(entries []DictEntry, config ExportConfig) {
+	if config.PreserveOrder {
+		return
+	}
+	switch config.SortBy {
+	case "word":
+		sortByWord(entries)
+	case "weight":
+		sortByWeight(entries)
+	default:
+		sortByCode(entries)
+	}
+}
+
+func isWeight(s string) bool {
+	_, ok := parseWeight(s)
+	return ok
+}
+
+func parseWeight(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	weight, err := strconv.ParseFloat(s, 64)
+	return weight, err == nil
+}
+
+// writeCodeWordPairs writes "code word" lines de-duplicated by code and returns the written entries
+// With config.MaxEntries only the first entries in sort order are written
+// With config.Append the entries already in the file are merged in after the new ones, so new words win
+func writeCodeWordPairs(path string, entries []DictEntry, config ExportConfig) ([]DictEntry, error) {
+	if config.Append {
+		existing, err := readCodeWordFile(path, false, config.Separator, config.DictSplit == "tab")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read existing output '%s': %w", path, err)
+		}
+		entries = append(slices.Clone(entries), existing...)
+	}
+
+	file, err := createTextAtomic(path, config)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Abort()
+
+	sortEntries(entries, config)
+
+	var written []DictEntry
+	if config.MultiWord {
+		written, err = writeMultiWordLines(file, entries, config.Separator, config.MaxEntries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write to '%s': %w", path, err)
+		}
+	} else {
+		seenCodes := make(map[string]bool)
+		for _, entry := range entries {
+			if config.MaxEntries > 0 && len(written) == config.MaxEntries {
+				break
+			}
+			if seenCodes[entry[0]] {
+				continue
+			}
+			seenCodes[entry[0]] = true
+			if _, err := file.WriteString(entry[0] + config.Separator + entry[1] + "\n"); err != nil {
+				return nil, fmt.Errorf("failed to write to '%s': %w", path, err)
+			}
+			written = append(written, entry)
+		}
+	}
+	if err := file.Commit(); err != nil {
+		return nil, err
+	}
+	config.progress.Add(1)
+	slog.Info("wrote output", "file", path, "entries", len(written))
+	return written, nil
+}
+
+// writeMultiWordLines writes one "code word1 word2" line per code, in order of each code's first entry
+// Repeated (code, word) pairs are written once; the returned entries are the pairs written,
+// at most maxEntries of them unless it is 0
+func writeMultiWordLines(w io.Writer, entries []DictEntry, sep string, maxEntries int) ([]DictEntry, error) {
+	var codes []string
+	words := make(map[string][]string)
+	var written []DictEntry
+	seen := make(map[[2]string]bool)
+	for _, entry := range entries {
+		if maxEntries > 0 && len(written) == maxEntries {
+			break
+		}
+		key := [2]string{entry[0], entry[1]}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := words[entry[0]]; !ok {
+			codes = append(codes, entry[0])
+		}
+		words[entry[0]] = append(words[entry[0]], entry[1])
+		written = append(written, entry)
+	}
+
+	for _, code := range codes {
+		if _, err := io.WriteString(w, code+sep+strings.Join(words[code], " ")+"\n"); err != nil {
+			return nil, err
+		}
+	}
+	return written, nil
+}
+
+// exportRoot writes roots.txt, and roots_<suffix>.txt for each suffixed roots CSV of config.RootPath, see findRootFiles
+func exportRoot(ctx context.Context, config ExportConfig) ([]FileStats, error) {
+	files, err := findRootFiles(config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	var stats []FileStats
+	// Export in suffix order, so logs, stats and the first failure do not depend on map iteration
+	for _, suffix := range slices.Sorted(maps.Keys(files)) {
+		csvPath := files[suffix]
+		fileStats, err := exportRootFile(ctx, csvPath, suffix, config)
+		stats = append(stats, fileStats...)
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// exportRootFile writes the roots of csvPath to the roots output of suffix, and their pinyin with config.ExportPinyin
+func exportRootFile(ctx context.Context, csvPath, suffix string, config ExportConfig) ([]FileStats, error) {
+	outputPath, err := resolveOutputPath(config, "roots", suffix)
+	if err != nil {
+		return nil, err
+	}
+	outputFile, err := createTextAtomic(outputPath, config)
+	if err != nil {
+		return nil, err
+	}
+	defer outputFile.Abort()
+
+	entries, pinyins, err := readRootsFromCSV(ctx, csvPath, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roots from CSV: %w", err)
+	}
+
+	// 写入排序后的条目；按字根排序时同一字根的多个编码都保留，按编码顺序排列
+	if !config.PreserveOrder {
+		if config.RootSortBy == "word" {
+			sortByWord(entries)
+		} else {
+			sortByCode(entries)
+		}
+	}
+	entries = limitEntries(entries, config.MaxEntries)
+	for _, entry := range entries {
+		if _, err := outputFile.WriteString(entry[1] + config.Separator + entry[0] + "\n"); err != nil {
+			return nil, fmt.Errorf("failed to write to '%s': %w", outputPath, err)
+		}
+	}
+	if err := outputFile.Commit(); err != nil {
+		return nil, err
+	}
+	config.progress.Add(1)
+	slog.Info("wrote output", "file", outputPath, "entries", len(entries))
+
+	stats := []FileStats{{File: outputPath, Category: "roots", Suffix: suffix, Entries: len(entries), written: entries}}
+	if config.ExportPinyin {
+		pinyinStats, err := exportRootPinyin(pinyins, suffix, config)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, pinyinStats)
+	}
+	return stats, nil
+}
+
+// exportRootPinyin writes the roots_pinyin output of suffix, one "word pinyin" line per root, sorted by word
+func exportRootPinyin(pinyins []DictEntry, suffix string, config ExportConfig) (FileStats, error) {
+	outputPath, err := resolveOutputPath(config, "roots_pinyin", suffix)
+	if err != nil {
+		return FileStats{}, err
+	}
+	if !config.PreserveOrder {
+		sortByWord(pinyins)
+	}
+	pinyins = limitEntries(pinyins, config.MaxEntries)
+	var buf bytes.Buffer
+	for _, entry := range pinyins {
+		buf.WriteString(entry[1] + config.Separator + entry[0] + "\n")
+	}
+	if err := writeTextFileAtomic(outputPath, buf.Bytes(), config); err != nil {
+		return FileStats{}, err
+	}
+	config.progress.Add(1)
+	slog.Info("wrote output", "file", outputPath, "entries", len(pinyins))
+
+	return FileStats{File: outputPath, Category: "roots_pinyin", Suffix: suffix, Entries: len(pinyins), written: pinyins}, nil
+}
+
+// limitEntries returns the first max entries, or all of them when max is 0
+func limitEntries(entries []DictEntry, max int) []DictEntry {
+	if max > 0 && len(entries) > max {
+		return entries[:max]
+	}
+	return entries
+}
+
+// readRootsFromCSV 从 CSV 文件读取字根，每行第一列是字根，第二列是编码
+// 字段以 config.CSVDelimiter 分隔，支持带引号的字段；空行和以 config.CommentChar 开头的注释行会被跳过
+// 编码必须是英文字母；非法行默认跳过并警告，--strict 时报错
+func readRootsFromCSV(ctx context.Context, csvPath string, config ExportConfig) (roots, pinyins []DictEntry, err error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open '%s': %w", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma, _ = utf8.DecodeRuneInString(config.CSVDelimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	var invalidLines []int
+	skipped := 0
+	isFirstLine := true
+	for count := 1; ; count++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		if count%cancelCheckLines == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+		// 跳过头部
+		if isFirstLine {
+			isFirstLine = false
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(fields[0]), config.CommentChar) {
+			continue
+		}
+		if len(fields) < 2 {
+			if strings.TrimSpace(fields[0]) != "" {
+				skipped++
+			}
+			continue
+		}
+		// CSV 格式: font,code,pinyin (第一列是字根，第二列是编码)
+		word := normalizeWord(trimCSVField(fields[0]), config)
+		code := normalizeCodeCase(trimCSVField(fields[1]), config.CodeCase)
+		if code == "" || word == "" {
+			skipped++
+			continue
+		}
+		if !isValidCode(code, config.codeChars) {
+			lineNum, _ := reader.FieldPos(0)
+			invalidLines = append(invalidLines, lineNum)
+			continue
+		}
+		if config.ignore.matches(code, word) {
+			continue
+		}
+		code = config.codeMap.apply(code)
+		roots = append(roots, DictEntry{code, word})
+		// 第三列是拼音，没有拼音的字根只从 roots_pinyin.txt 中省略
+		if len(fields) > 2 {
+			if pinyin := trimCSVField(fields[2]); pinyin != "" {
+				pinyins = append(pinyins, DictEntry{pinyin, word})
+			}
+		}
+	}
+
+	if len(invalidLines) > 0 {
+		if config.Strict {
+			return nil, nil, fmt.Errorf("invalid root codes in '%s' at lines %s", csvPath, joinInts(invalidLines))
+		}
+		slog.Warn("skipped roots with invalid codes", "file", csvPath, "count", len(invalidLines))
+	}
+	config.sources.add(csvPath, len(roots), skipped+len(invalidLines))
+	return roots, pinyins, nil
+}
+
+func exportQuickWords(ctx context.Context, config ExportConfig) ([]FileStats, error) {
+	var stats []FileStats
+
+	// Export main quick file (no suffix)
+	mainPath := mainDictPath(config.YuhaoPath, config.MethodName, "quick")
+	if _, err := os.Stat(mainPath); err == nil {
+		fileStats, err := exportQuickWordsFromFile(ctx, mainPath, "", config)
+		stats = append(stats, fileStats...)
+		if err != nil {
+			return stats, &FileError{File: mainPath, Err: err}
+		}
+	}
+
+	// Find and export suffixed quick files
+	suffixedFiles := findSuffixedFiles(config.YuhaoPath, config.MethodName, "quick")
+	for _, suffix := range slices.Sorted(maps.Keys(suffixedFiles)) {
+		filePath := suffixedFiles[suffix]
+		fileStats, err := exportQuickWordsFromFile(ctx, filePath, suffix, config)
+		stats = append(stats, fileStats...)
+		if err != nil {
+			return stats, &FileError{File: filePath, Err: err}
+		}
+	}
+	return stats, nil
+}
+
+func exportQuickWordsFromFile(ctx context.Context, dictPath, suffix string, config ExportConfig) ([]FileStats, error) {
+	words, chars, err := readDictEntries(ctx, dictPath, "quick", config)
+	if err != nil {
+		return nil, err
+	}
+
+	words = filterByCodeLength(words, dictPath, config)
+	chars = filterByCodeLength(chars, dictPath, config)
+	return writeWordsAndChars("quick", suffix, words, chars, config)
+}
+
+func exportPopWords(ctx context.Context, config ExportConfig) ([]FileStats, error) {
+	var stats []FileStats
+
+	// Export main pop file (no suffix)
+	mainPath := mainDictPath(config.YuhaoPath, config.MethodName, "pop")
+	if _, err := os.Stat(mainPath); err == nil {
+		fileStats, err := exportPopWordsFromFile(ctx, mainPath, "", config)
+		stats = append(stats, fileStats...)
+		if err != nil {
+			return stats, &FileError{File: mainPath, Err: err}
+		}
+	}
+
+	// Find and export suffixed pop files
+	suffixedFiles := findSuffixedFiles(config.YuhaoPath, config.MethodName, "pop")
+	for _, suffix := range slices.Sorted(maps.Keys(suffixedFiles)) {
+		filePath := suffixedFiles[suffix]
+		fileStats, err := exportPopWordsFromFile(ctx, filePath, suffix, config)
+		stats = append(stats, fileStats...)
+		if err != nil {
+			return stats, &FileError{File: filePath, Err: err}
+		}
+	}
+	return stats, nil
+}
+
+func exportPopWordsFromFile(ctx context.Context, dictPath, suffix string, config ExportConfig) ([]FileStats, error) {
+	words, chars, err := readDictEntries(ctx, dictPath, "pop", config)
+	if err != nil {
+		return nil, err
+	}
+
+	words = filterByCodeLength(words, dictPath, config)
+	chars = filterByCodeLength(chars, dictPath, config)
+	return writeWordsAndChars("pop", suffix, words, chars, config)
+}
+
+// writeWordsAndChars writes the words and chars of a quick or pop dict to fileType_words and fileType_chars,
+// and with config.Combine also both together to a fileType file such as quick.txt, chars first
+func writeWordsAndChars(fileType, suffix string, words, chars []DictEntry, config ExportConfig) ([]FileStats, error) {
+	var stats []FileStats
+	write := func(category string, entries []DictEntry) error {
+		path, err := resolveOutputPath(config, category, suffix)
+		if err != nil {
+			return err
+		}
+		written, err := writeCodeWordPairs(path, entries, config)
+		if err != nil {
+			return err
+		}
+		stats = append(stats, FileStats{File: path, Category: category, Suffix: suffix, Entries: len(written), written: written, parsed: entries})
+		return nil
+	}
+
+	if config.Combine != "only" {
+		if err := write(fileType+"_words", words); err != nil {
+			return stats, err
+		}
+		if err := write(fileType+"_chars", chars); err != nil {
+			return stats, err
+		}
+	}
+	if config.Combine != "" {
+		if err := write(fileType, append(slices.Clone(chars), words...)); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// cancelCheckLines is how often the dict and root readers check for cancellation
+const cancelCheckLines = 1024
+
+// readDictEntries parses a rime dict file and splits its valid entries into words and chars
+// The YAML header (between "---" and "...") is read for its columns directive only
+// fileType (quick or pop) selects the explicit word and code columns of config, see dictColumnFlags
+func readDictEntries(ctx context.Context, dictPath, fileType string, config ExportConfig) (words, chars []DictEntry, err error) {
+	file, err := os.Open(dictPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open '%s': %w", dictPath, err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(dictPath, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress '%s': %w", dictPath, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	skipped := 0
+	var tooLongLines []int
+	shortLines := 0
+	wordCol, codeCol, explicit := dictColumnFlags(fileType, config)
+	inHeader := false
+	var header strings.Builder
+	cols := defaultDictColumns
+	for scanner.Scan() {
+		line := trimLine(scanner.Text(), lineNum == 0)
+		lineNum++
+		if lineNum%cancelCheckLines == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Skip YAML header
+		if lineNum == 1 && strings.TrimSpace(line) == "---" {
+			inHeader = true
+			continue
+		}
+		if inHeader {
+			if strings.TrimSpace(line) == "..." {
+				inHeader = false
+				cols = parseDictColumns(header.String(), dictPath)
+			} else {
+				header.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if config.Strict {
+			if reason := checkDictLine(line, cols, config.DictSplit == "tab"); reason != "" {
+				config.rejected.add(dictPath, lineNum, reason, line)
+				skipped++
+				continue
+			}
+		}
+
+		// Rime dict line: word and code in the order of the header's columns, with an optional numeric weight
+		// Explicit word and code columns allow any number of extra columns
+		fields := splitDictLine(line, config.DictSplit)
+		weight := ""
+		if explicit {
+			cols.text, cols.code = wordCol, codeCol
+			if len(fields) <= max(wordCol, codeCol) {
+				shortLines++
+				skipped++
+				continue
+			}
+			if cols.weight >= 0 && cols.weight < len(fields) && isWeight(strings.TrimSpace(fields[cols.weight])) {
+				weight = strings.TrimSpace(fields[cols.weight])
+			}
+		} else if len(fields) == 3 && cols.weight >= 0 && cols.weight < len(fields) && isWeight(fields[cols.weight]) {
+			weight = strings.TrimSpace(fields[cols.weight])
+		} else if len(fields) != 2 || max(cols.text, cols.code) >= 2 {
+			skipped++
+			continue
+		}
+		word, code := normalizeWord(fields[cols.text], config), normalizeCodeCase(stripToneMarks(strings.TrimSpace(fields[cols.code]), config.toneMarks), config.CodeCase)
+		if code == "" || !isValidCode(code, config.codeChars) || (!config.IncludeASCII && isAllASCII(word)) {
+			skipped++
+			continue
+		}
+		if config.MaxKeyLen > 0 && len(code) > config.MaxKeyLen {
+			tooLongLines = append(tooLongLines, lineNum)
+			skipped++
+			continue
+		}
+		if config.ignore.matches(code, word) {
+			continue
+		}
+		code = config.codeMap.apply(code)
+		if wordLength(word, config) > 1 {
+			words = append(words, DictEntry{code, word, weight})
+		} else {
+			chars = append(chars, DictEntry{code, word, weight})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading dictionary: %w", err)
+	}
+
+	if shortLines > 0 {
+		slog.Warn("skipped lines with fewer columns than the word and code columns", "file", dictPath, "count", shortLines)
+	}
+	if len(tooLongLines) > 0 {
+		if config.Strict {
+			return nil, nil, fmt.Errorf("codes longer than %d keys in '%s' at lines %s", config.MaxKeyLen, dictPath, joinInts(tooLongLines))
+		}
+		slog.Warn("skipped entries with codes longer than the max key length", "file", dictPath, "max", config.MaxKeyLen, "count", len(tooLongLines))
+	}
+	config.sources.add(dictPath, len(words)+len(chars), skipped)
+	return words, chars, nil
+}
+
+// dictColumnFlags returns the 0-based word and code columns set for fileType (quick or pop) in config,
+// where the 1-based --*-word-col and --*-code-col flags default to 1 and 2; explicit is false if neither is set
+func dictColumnFlags(fileType string, config ExportConfig) (wordCol, codeCol int, explicit bool) {
+	wordCol, codeCol = config.QuickWordCol, config.QuickCodeCol
+	if fileType == "pop" {
+		wordCol, codeCol = config.PopWordCol, config.PopCodeCol
+	}
+	if wordCol == 0 && codeCol == 0 {
+		return 0, 0, false
+	}
+	if wordCol == 0 {
+		wordCol = 1
+	}
+	if codeCol == 0 {
+		codeCol = 2
+	}
+	return wordCol - 1, codeCol - 1, true
+}
+
+// checkDictLine reports why a dict line is malformed, or "" if it is fine
+// Lines without a tab are split on whitespace, so only control characters can be found in their fields
+// With tabSplit, spaces in the word are allowed
+func checkDictLine(line string, cols dictColumns, tabSplit bool) string {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ""
+	}
+	columns := strings.Split(line, "\t")
+	if len(columns) < 2 {
+		columns = strings.Fields(line)
+	}
+	for i, column := range columns {
+		if i != cols.text && i != cols.code {
+			continue
+		}
+		if reason := checkDictField(column, tabSplit && i == cols.text); reason != "" {
+			if i == cols.code {
+				return reason + " in code"
+			}
+			return reason + " in word"
+		}
+	}
+	return ""
+}
+
+// splitDictLine splits a dict line into fields on any whitespace, or with split "tab" on tabs only,
+// keeping spaces inside and around the word; codes and weights are trimmed by their parsers
+func splitDictLine(line, split string) []string {
+	if split != "tab" {
+		return strings.Fields(line)
+	}
+	fields := strings.Split(line, "\t")
+	for len(fields) > 0 && strings.TrimSpace(fields[len(fields)-1]) == "" {
+		fields = fields[:len(fields)-1]
+	}
+	return fields
+}
+
+// dictColumns holds the field positions of text, code and weight in a rime dict line; weight is -1 when absent
+type dictColumns struct {
+	text, code, weight int
+}
+
+// defaultDictColumns is the rime default order: text, code, weight
+var defaultDictColumns = dictColumns{text: 0, code: 1, weight: 2}
+
+// parseDictColumns reads the columns directive of a dict's YAML header, e.g. "columns: [code, text]"
+// Headers without a usable directive fall back to defaultDictColumns
+func parseDictColumns(header, dictPath string) dictColumns {
+	var meta struct {
+		Columns []string `yaml:"columns"`
+	}
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		slog.Warn("failed to parse dict header, assuming text and code columns", "file", dictPath, "error", err)
+		return defaultDictColumns
+	}
+	if len(meta.Columns) == 0 {
+		return defaultDictColumns
+	}
+
+	cols := dictColumns{text: -1, code: -1, weight: -1}
+	for i, column := range meta.Columns {
+		switch column {
+		case "text":
+			cols.text = i
+		case "code":
+			cols.code = i
+		case "weight":
+			cols.weight = i
+		}
+	}
+	if cols.text < 0 || cols.code < 0 {
+		slog.Warn("dict columns lack text or code, assuming text and code columns", "file", dictPath, "columns", meta.Columns)
+		return defaultDictColumns
+	}
+	return cols
+}
+
+func checkDictField(field string, allowSpace bool) string {
+	for _, r := range field {
+		if unicode.IsControl(r) {
+			return "control character"
+		}
+		if unicode.IsSpace(r) && !allowSpace {
+			return "whitespace"
+		}
+	}
+	return ""
+}
+
+// sourceCounts collects how many lines of each input file were used and skipped
+type sourceCounts struct {
+	stats []SourceStats
+}
+
+func (c *sourceCounts) add(path string, entries, skipped int) {
+	if c == nil {
+		return
+	}
+	c.stats = append(c.stats, SourceStats{File: path, Entries: entries, Skipped: skipped})
+}
+
+// rejectedLines collects dictionary lines dropped by strict validation
+type rejectedLines struct {
+	lines []string
+}
+
+func (r *rejectedLines) add(dictPath string, lineNum int, reason, line string) {
+	if r == nil {
+		return
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s:%d\t%s\t%q", filepath.Base(dictPath), lineNum, reason, line))
+}
+
+// writeRejected writes rejected lines to rejected.txt in the target directory
+func writeRejected(config ExportConfig) error {
+	path := filepath.Join(config.TargetPath, "rejected.txt")
+	content := strings.Join(config.rejected.lines, "\n")
+	if content != "" {
+		content += "\n"
+		slog.Warn("rejected malformed dictionary lines", "count", len(config.rejected.lines), "file", path)
+	}
+	if err := writeTextFileAtomic(path, []byte(content), config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkEmptyOutputs warns about output files with no entries, which usually point to a misnamed dict file
+// With failOnEmpty it returns an error naming them instead
+func checkEmptyOutputs(files []FileStats, failOnEmpty bool) error {
+	var empty []string
+	for _, file := range files {
+		if file.Entries > 0 {
+			continue
+		}
+		if failOnEmpty {
+			empty = append(empty, file.File)
+		} else {
+			slog.Warn("output file is empty", "file", file.File)
+		}
+	}
+	if len(empty) > 0 {
+		return fmt.Errorf("empty output files: %s", strings.Join(empty, ", "))
+	}
+	return nil
+}
+
+// filterByCodeLength drops entries whose code length is outside [MinCodeLen, MaxCodeLen]
+// A zero bound means no limit on that side
+func filterByCodeLength(entries []DictEntry, dictPath string, config ExportConfig) []DictEntry {
+	if config.MinCodeLen <= 0 && config.MaxCodeLen <= 0 {
+		return entries
+	}
+
+	kept := entries[:0]
+	for _, entry := range entries {
+		codeLen := len(entry[0])
+		if config.MinCodeLen > 0 && codeLen < config.MinCodeLen {
+			continue
+		}
+		if config.MaxCodeLen > 0 && codeLen > config.MaxCodeLen {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	if filtered := len(entries) - len(kept); filtered > 0 {
+		slog.Debug("filtered entries by code length", "file", dictPath, "count", filtered)
+	}
+	return kept
+}
+
+// wordLength counts the visible characters of word: grapheme clusters by default, runes with --split-by rune
+func wordLength(word string, config ExportConfig) int {
+	if config.SplitBy == "rune" {
+		return len([]rune(word))
+	}
+	return uniseg.GraphemeClusterCount(word)
+}
+
+func joinInts(nums []int) string {
+	strs := make([]string, len(nums))
+	for i, n := range nums {
+		strs[i] = strconv.Itoa(n)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// normalizeCodeCase applies the --code-case convention to a code: lower (the default), upper or preserve
+func normalizeCodeCase(code, codeCase string) string {
+	switch codeCase {
+	case "preserve":
+		return code
+	case "upper":
+		return strings.ToUpper(code)
+	default:
+		return strings.ToLower(code)
+	}
+}
+
+// trimCSVField trims the spaces around a CSV field and a pair of double quotes left around it,
+// as in ` "土"`, which encoding/csv reads as a bare field because of the space before the quote
+func trimCSVField(field string) string {
+	field = strings.TrimSpace(field)
+	if len(field) >= 2 && field[0] == '"' && field[len(field)-1] == '"' {
+		field = strings.ReplaceAll(field[1:len(field)-1], `""`, `"`)
+	}
+	return field
+}
+
+// normalizeWord applies config.UnicodeNormalize to word, then with config.NormalizeWidth folds full-width
+// characters to their half-width forms, e.g. "ＡＢＣ！" to "ABC!"; half-width katakana become full-width
+func normalizeWord(word string, config ExportConfig) string {
+	switch config.UnicodeNormalize {
+	case "nfc":
+		word = norm.NFC.String(word)
+	case "nfd":
+		word = norm.NFD.String(word)
+	}
+	if config.NormalizeWidth {
+		word = width.Fold.String(word)
+	}
+	return word
+}
+
+// isValidCode reports whether s has only English letters and characters of extra
+func isValidCode(s, extra string) bool {
+	for _, r := range s {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) && !strings.ContainsRune(extra, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripToneMarks removes the tone-number characters of marks from code, e.g. "ni3hao3" to "nihao"
+func stripToneMarks(code, marks string) string {
+	if marks == "" {
+		return code
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(marks, r) {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// expandCodeChars expands ranges like "0-9" in a --code-chars value into the characters they cover
+// A "-" at the start or end is taken literally
+func expandCodeChars(spec string) (string, error) {
+	runes := []rune(spec)
+	var chars strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if i+2 < len(runes) && runes[i+1] == '-' {
+			if runes[i] > runes[i+2] {
+				return "", fmt.Errorf("invalid code character range: %s", string(runes[i:i+3]))
+			}
+			for r := runes[i]; r <= runes[i+2]; r++ {
+				chars.WriteRune(r)
+			}
+			i += 2
+			continue
+		}
+		chars.WriteRune(runes[i])
+	}
+	return chars.String(), nil
+}
+
+func isAllASCII(s string) bool {
+	for _, r := range s {
+		if r > 127 {
+			return false
+		}
+	}
+	return true
+}
+
+// extractZipToDir extracts every entry of the zip at zipPath into destDir
+// Symlink entries are recreated with opts.AllowSymlinks when their target stays inside destDir, see extractSymlink;
+// otherwise they are skipped with a warning
+func extractZipToDir(ctx context.Context, zipPath, destDir string, opts ExtractOptions) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	bar := newProgress("Extracting", len(r.File), opts.Quiet)
+	defer bar.Finish()
+	for _, file := range r.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			if !opts.AllowSymlinks {
+				slog.Warn("skipping symlink in zip (pass --allow-symlinks to extract it)", "entry", file.Name)
+			} else if err := extractSymlink(file, destDir); err != nil {
+				return err
+			}
+			bar.Add(1)
+			continue
+		}
+		if err := extractFile(file, destDir); err != nil {
+			return err
+		}
+		bar.Add(1)
+	}
+
+	// Directory times are set last, extracting files into a directory updates its mtime
+	for _, file := range r.File {
+		if !file.FileInfo().IsDir() {
+			continue
+		}
+		name, err := normalizeZipEntryName(file.Name)
+		if err != nil || name == "." {
+			continue
+		}
+		modTime := zipEntryModTime(file)
+		if err := os.Chtimes(filepath.Join(destDir, filepath.FromSlash(name)), modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipEntryModTime returns the modification time stored for a zip entry, or now if it is missing or invalid
+func zipEntryModTime(file *zip.File) time.Time {
+	// DOS timestamps cannot represent dates before 1980
+	if file.Modified.IsZero() || file.Modified.Year() < 1980 {
+		return time.Now()
+	}
+	return file.Modified
+}
+
+// zipDir packages every file under srcDir into zipPath, keeping paths relative to srcDir
+// If zipPath lives inside srcDir it is skipped so the archive never contains itself
+func zipDir(srcDir, zipPath string) error {
+	absZipPath, err := filepath.Abs(zipPath)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	err = filepath.WalkDir(srcDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if absPath, err := filepath.Abs(path); err == nil && absPath == absZipPath {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		return addFileToZip(w, path, filepath.ToSlash(relPath))
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func addFileToZip(w *zip.Writer, filePath, name string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	dst, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func extractFile(file *zip.File, destDir string) error {
+	name, err := normalizeZipEntryName(file.Name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	filePath := filepath.Join(destDir, filepath.FromSlash(name))
+
+	// Prevent ZipSlip
+	if !strings.HasPrefix(filePath, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return fmt.Errorf("illegal file path: %s", filePath)
+	}
+
+	if file.FileInfo().IsDir() {
+		return os.MkdirAll(filePath, os.ModePerm)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	modTime := zipEntryModTime(file)
+	return os.Chtimes(filePath, modTime, modTime)
+}
+
+// maxSymlinkTarget bounds the size of a symlink entry read from a zip, whose content is the link target
+const maxSymlinkTarget = 4096
+
+// extractSymlink recreates a symlink entry of a zip under destDir
+// The target must be relative and, resolved from the real directory of the link, stay inside destDir;
+// a target with ".." must also already exist, so that later entries cannot redirect it outside
+func extractSymlink(file *zip.File, destDir string) error {
+	name, err := normalizeZipEntryName(file.Name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return fmt.Errorf("illegal symlink in zip: %s", file.Name)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(src, maxSymlinkTarget+1))
+	src.Close()
+	if err != nil {
+		return err
+	}
+	target := string(data)
+	if target == "" || len(data) > maxSymlinkTarget || strings.ContainsRune(target, 0) {
+		return fmt.Errorf("illegal symlink target in zip: %s", file.Name)
+	}
+	if path.IsAbs(target) || filepath.IsAbs(target) || strings.Contains(target, "\\") {
+		return fmt.Errorf("illegal symlink in zip: %s -> %s points outside the destination", file.Name, target)
+	}
+
+	linkPath := filepath.Join(destDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(linkPath), os.ModePerm); err != nil {
+		return err
+	}
+	// Earlier symlinks may lead the parent elsewhere inside destDir, so the target is resolved from the real parent
+	realDest, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(linkPath))
+	if err != nil {
+		return err
+	}
+	resolved := filepath.Join(realParent, filepath.FromSlash(target))
+	if !isWithinDir(resolved, realDest) {
+		return fmt.Errorf("illegal symlink in zip: %s -> %s points outside the destination", file.Name, target)
+	}
+	if slices.Contains(strings.Split(path.Clean(target), "/"), "..") {
+		if resolved, err = filepath.EvalSymlinks(resolved); err != nil {
+			return fmt.Errorf("illegal symlink in zip: %s -> %s: target with '..' must exist: %w", file.Name, target, err)
+		}
+	}
+	if !isWithinDir(resolved, realDest) {
+		return fmt.Errorf("illegal symlink in zip: %s -> %s points outside the destination", file.Name, target)
+	}
+
+	if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(target), linkPath)
+}
+
+// isWithinDir reports whether path is dir or lies under it, both clean absolute paths
+func isWithinDir(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// normalizeZipEntryName turns a zip entry name into a clean relative slash path
+// Backslashes from Windows-created zips become separators and leading slashes or drive letters are dropped,
+// so "/etc/passwd" stays inside the destination; names escaping it, like "../evil" or "foo\..\..\bar", are rejected
+func normalizeZipEntryName(name string) (string, error) {
+	normalized := strings.ReplaceAll(name, "\\", "/")
+	if len(normalized) >= 2 && normalized[1] == ':' {
+		normalized = normalized[2:]
+	}
+	normalized = path.Clean(strings.TrimLeft(normalized, "/"))
+	if normalized == ".." || strings.HasPrefix(normalized, "../") {
+		return "", fmt.Errorf("illegal file path in zip: %s", name)
+	}
+	return normalized, nil
+}
+
+// exportTemplate reads methodName.template.toml, updates configversion, and writes to target directory
+func exportTemplate(config ExportConfig) ([]TemplateStats, error) {
+	templateDir, err := templateSourceDir(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []TemplateStats
+
+	// Export main template file (no suffix)
+	mainTemplatePath := filepath.Join(templateDir, config.MethodName+".template.toml")
+	if _, err := os.Stat(mainTemplatePath); err == nil {
+		tmplStats, err := exportTemplateFromFile(mainTemplatePath, config.MethodName+".toml", "", config)
+		if err != nil {
+			return stats, &FileError{File: mainTemplatePath, Err: fmt.Errorf("failed to export main template: %w", err)}
+		}
+		stats = append(stats, tmplStats)
+	}
+
+	// Find and export suffixed template files
+	suffixedTemplates := findSuffixedTemplates(templateDir, config.MethodName, "template.toml")
+	for _, suffix := range slices.Sorted(maps.Keys(suffixedTemplates)) {
+		filePath := suffixedTemplates[suffix]
+		outputName := config.MethodName + "_" + suffix + ".toml"
+		tmplStats, err := exportTemplateFromFile(filePath, outputName, suffix, config)
+		if err != nil {
+			return stats, &FileError{File: filePath, Err: fmt.Errorf("failed to export template '%s': %w", outputName, err)}
+		}
+		stats = append(stats, tmplStats)
+	}
+
+	return stats, nil
+}
+
+// templateSourceDir returns the directory templates are read from: config.TemplateDir when set, otherwise the first
+// of the source's schema directory and dict directory that has a template of the method, falling back to the current directory
+// With config.Update, templates of an extracted zip or URL source are refused, as the update would be lost with the temp directory
+func templateSourceDir(config ExportConfig) (string, error) {
+	if config.TemplateDir != "" {
+		return config.TemplateDir, nil
+	}
+	for _, dir := range []string{config.schemaDir, config.YuhaoPath} {
+		if dir != "" && hasTemplates(dir, config.MethodName) {
+			if config.Update && config.extracted {
+				return "", fmt.Errorf("--update cannot write to the templates extracted from the source to '%s', set --template-dir to the templates to update", dir)
+			}
+			return dir, nil
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return cwd, nil
+}
+
+// hasTemplates reports whether dir holds methodName.template.toml or a suffixed variant of it
+func hasTemplates(dir, methodName string) bool {
+	if _, err := os.Stat(filepath.Join(dir, methodName+".template.toml")); err == nil {
+		return true
+	}
+	return len(findSuffixedTemplates(dir, methodName, "template.toml")) > 0
+}
+
+// findSuffixedTemplates finds files matching pattern: methodName_*.suffix
+// Returns map of suffix -> file path
+func findSuffixedTemplates(cwd, methodName, suffix string) map[string]string {
+	result := make(map[string]string)
+
+	entries, err := os.ReadDir(cwd)
+	if err != nil {
+		return result
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if isIgnoredFile(name) {
+			slog.Debug("skipped hidden or backup file", "file", name)
+			continue
+		}
+		if !strings.HasPrefix(name, methodName+"_") || !strings.HasSuffix(name, "."+suffix) {
+			continue
+		}
+		// Extract suffix: methodName_suffix.suffix -> suffix
+		middle := strings.TrimPrefix(name, methodName+"_")
+		fileSuffix := strings.TrimSuffix(middle, "."+suffix)
+		if fileSuffix != "" && middle != fileSuffix {
+			result[fileSuffix] = filepath.Join(cwd, name)
+		}
+	}
+	return result
+}
+
+// readCodeWordFile reads an exported text file into code-word entries
+// roots.txt format: "word keyCode" (isRoots); others format: "code word",
+// or "code word1 word2" as written by --multi-word, which yields one entry per word
+// With strictSep lines are split on sep exactly and each field after the code is one word, keeping its spaces
+func readCodeWordFile(path string, isRoots bool, sep string, strictSep bool) ([]DictEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []DictEntry
+	scanner := bufio.NewScanner(file)
+	isFirstLine := true
+	for scanner.Scan() {
+		line := trimLine(scanner.Text(), isFirstLine)
+		isFirstLine = false
+		fields := splitCodeWordLine(line, sep)
+		if strictSep {
+			fields = strings.Split(line, sep)
+		}
+		if isRoots {
+			if len(fields) == 2 {
+				entries = append(entries, DictEntry{fields[1], fields[0]})
+			}
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		if strictSep {
+			// Each field after the code is a whole word, spaces included
+			for _, word := range fields[1:] {
+				if word != "" {
+					entries = append(entries, DictEntry{fields[0], word})
+				}
+			}
+			continue
+		}
+		for _, word := range strings.Fields(strings.Join(fields[1:], " ")) {
+			entries = append(entries, DictEntry{fields[0], word})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	return entries, nil
+}
+
+// trimLine strips the trailing "\r" left by CRLF line endings, and a UTF-8 BOM on the first line
+func trimLine(line string, isFirstLine bool) string {
+	if isFirstLine {
+		line = strings.TrimPrefix(line, "\ufeff")
+	}
+	return strings.TrimSuffix(line, "\r")
+}
+
+// splitCodeWordLine splits an exported line on sep, or on any whitespace when sep is blank
+func splitCodeWordLine(line, sep string) []string {
+	if strings.TrimSpace(sep) == "" {
+		return strings.Fields(line)
+	}
+	return strings.Split(line, sep)
+}
+
+// UnescapeSeparator interprets escape sequences such as "\t" in a separator flag value
+// Values that are not valid escape sequences are used literally
+func UnescapeSeparator(sep string) string {
+	if sep == "" {
+		return "\t"
+	}
+	if unquoted, err := strconv.Unquote(`"` + sep + `"`); err == nil {
+		return unquoted
+	}
+	return sep
+}
+
+// defaultNameTemplate produces the built-in output names, e.g. "quick_words.txt" or "quick_words_tc.txt"
+const defaultNameTemplate = `{{.Category}}{{if .Suffix}}_{{.Suffix}}{{end}}.{{.Ext}}`
+
+// outputNameData holds the variables available to --name-template
+type outputNameData struct {
+	Category string
+	Suffix   string
+	Method   string
+	Ext      string
+}
+
+// parseNameTemplate parses an output naming template, falling back to defaultNameTemplate
+func parseNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultNameTemplate
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// resolveOutputPath returns the target path of a category output file such as roots or quick_words
+func resolveOutputPath(config ExportConfig, category, suffix string) (string, error) {
+	tmpl := config.nameTemplate
+	if tmpl == nil {
+		var err error
+		if tmpl, err = parseNameTemplate(config.NameTemplate); err != nil {
+			return "", err
+		}
+	}
+
+	var name strings.Builder
+	data := outputNameData{Category: category, Suffix: suffix, Method: config.MethodName, Ext: "txt"}
+	if err := tmpl.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("failed to resolve output name for '%s': %w", category, err)
+	}
+	return filepath.Join(config.TargetPath, name.String()), nil
+}
+
+// outputCategories lists the category names of the text files produced by export
+// roots_pinyin comes before roots so it is not taken for roots with a "pinyin" suffix
+// quick and pop come last so they are not taken for quick_words etc. with a "words" suffix
+var outputCategories = []string{"roots_pinyin", "roots", "quick_words", "quick_chars", "pop_words", "pop_chars", "quick", "pop"}
+
+// parseOutputFileName splits an exported file name like "quick_words_tc.txt" into category and suffix
+// ok is false for files that are not export outputs
+func parseOutputFileName(name string) (category, suffix string, ok bool) {
+	base, found := strings.CutSuffix(name, ".txt")
+	if !found {
+		return "", "", false
+	}
+	for _, category := range outputCategories {
+		if base == category {
+			return category, "", true
+		}
+		if rest, found := strings.CutPrefix(base, category+"_"); found && rest != "" {
+			return category, rest, true
+		}
+	}
+	return "", "", false
+}
+
+// generateItemsFromMeta generates Items based on ItemsMeta rules
+// Category values are item names (CategoryItem), e.g., "quick_words", "pop_words", "roots"
+// File format: "CategoryItem_methodNameSuffix.txt" or "CategoryItem.txt"
+// roots.txt format: "word keyCode" (e.g., "土 GA")
+// others format: "code word" (e.g., "ga 土")
+// codeOrders lists the codes of each table in order of first insertion
+func generateItemsFromMeta(itemsMeta []TemplateItemsMeta, methodNameSuffix string, config ExportConfig) (items []map[string][]string, codeOrders [][]string, err error) {
+	items = make([]map[string][]string, len(itemsMeta))
+	codeOrders = make([][]string, len(itemsMeta))
+
+	// Category files are read from ItemsDir when set, otherwise from the export target
+	itemsConfig := config
+	if config.ItemsDir != "" {
+		itemsConfig.TargetPath = config.ItemsDir
+	}
+
+	for i, meta := range itemsMeta {
+		itemMap := make(map[string][]string)
+		seen := make(map[DictEntry]bool)
+
+		var codeRegex *regexp.Regexp
+		if meta.CodeRegex != "" {
+			codeRegex, err = regexp.Compile(meta.CodeRegex)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid code_regex in items_meta[%d] (category %v): %w", i, meta.Category, err)
+			}
+		}
+
+		for _, categoryItem := range meta.Category {
+			// Try different file patterns based on methodNameSuffix, most specific first
+			// In merge mode all existing files are read: on a code collision the suffixed file's
+			// words come before the base file's, and a word present in both is kept once
+			// In override mode only the first existing file is read
+			var suffixes []string
+			if methodNameSuffix != "" {
+				suffixes = []string{methodNameSuffix, ""}
+			} else {
+				suffixes = []string{""}
+			}
+
+			for _, suffix := range suffixes {
+				categoryFilePath, err := resolveOutputPath(itemsConfig, categoryItem, suffix)
+				if err != nil {
+					return nil, nil, err
+				}
+				if _, err := os.Stat(categoryFilePath); os.IsNotExist(err) {
+					continue
+				}
+
+				// Read and parse the category file
+				entries, err := readCodeWordFile(categoryFilePath, categoryItem == "roots", config.Separator, config.DictSplit == "tab")
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					code, word := entry[0], entry[1]
+
+					// Check Prefix (code must contain one of the prefixes)
+					if len(meta.Prefix) > 0 {
+						hasPrefix := false
+						for _, pre := range meta.Prefix {
+							if strings.HasPrefix(code, pre) {
+								hasPrefix = true
+								break
+							}
+						}
+						if !hasPrefix {
+							continue
+						}
+					}
+
+					// Check Suffix (code must contain one of the suffixes)
+					if len(meta.Suffix) > 0 {
+						hasSuffix := false
+						for _, suf := range meta.Suffix {
+							if strings.HasSuffix(code, suf) {
+								hasSuffix = true
+								break
+							}
+						}
+						if !hasSuffix {
+							continue
+						}
+					}
+
+					// Check ExcludePrefix (code must not start with any of them)
+					excluded := false
+					for _, pre := range meta.ExcludePrefix {
+						if strings.HasPrefix(code, pre) {
+							excluded = true
+							break
+						}
+					}
+
+					// Check ExcludeSuffix (code must not end with any of them)
+					for _, suf := range meta.ExcludeSuffix {
+						if strings.HasSuffix(code, suf) {
+							excluded = true
+							break
+						}
+					}
+					if excluded {
+						continue
+					}
+
+					// Check MinLength
+					if meta.MinLength > 0 && len(code) < meta.MinLength {
+						continue
+					}
+
+					// Check MaxLength
+					if meta.MaxLength > 0 && len(code) > meta.MaxLength {
+						continue
+					}
+
+					// Check CodeRegex
+					if codeRegex != nil && !codeRegex.MatchString(code) {
+						continue
+					}
+
+					// Apply AppendPrefix
+					if meta.AppendPrefix != "" {
+						code = meta.AppendPrefix + code
+					}
+
+					// Apply AppendSuffix
+					if meta.AppendSuffix != "" {
+						code = code + meta.AppendSuffix
+					}
+
+					// Add to item map
+					if key := (DictEntry{code, word}); !seen[key] {
+						seen[key] = true
+						if _, found := itemMap[code]; !found {
+							codeOrders[i] = append(codeOrders[i], code)
+						}
+						itemMap[code] = append(itemMap[code], word)
+					}
+				}
+				if config.ItemsMerge == "override" {
+					break
+				}
+			}
+		}
+
+		// Convert map to slice format
+		items[i] = make(map[string][]string)
+		for code, words := range itemMap {
+			items[i][code] = words
+		}
+	}
+
+	return items, codeOrders, nil
+}
+
+// sortItemCodes orders the codes of each items table for output; codeOrders is their source order
+// sortBy is code (default, as sortByCode), source, or word (by the first word of each code)
+// Except with source, which keeps the tables as read, the words of each code are sorted in place too
+func sortItemCodes(items []map[string][]string, codeOrders [][]string, sortBy string) [][]string {
+	if sortBy == "source" {
+		return codeOrders
+	}
+	sorted := make([][]string, len(codeOrders))
+	for i, codes := range codeOrders {
+		for _, words := range items[i] {
+			slices.Sort(words)
+		}
+		codes = slices.Clone(codes)
+		sort.SliceStable(codes, func(a, b int) bool {
+			if sortBy == "word" {
+				if wordA, wordB := items[i][codes[a]][0], items[i][codes[b]][0]; wordA != wordB {
+					return wordA < wordB
+				}
+			}
+			if len(codes[a]) != len(codes[b]) {
+				return len(codes[a]) < len(codes[b])
+			}
+			return codes[a] < codes[b]
+		})
+		sorted[i] = codes
+	}
+	return sorted
+}
+
+// itemsTable is an items table whose codes are encoded in the order of codes, since the TOML and YAML
+// encoders write map keys in plain lexical order; see MarshalYAML and encodeTOML
+type itemsTable[V any] struct {
+	codes  []string
+	values map[string]V
+}
+
+// orderedItems pairs each items table with its codes in the order of codeOrders
+func orderedItems[V any](items []map[string]V, codeOrders [][]string) []any {
+	ordered := make([]any, len(items))
+	for i, item := range items {
+		ordered[i] = itemsTable[V]{codes: codeOrders[i], values: item}
+	}
+	return ordered
+}
+
+// MarshalYAML encodes the table as a mapping node with its codes in order
+// Codes are string keys, quoted by the encoder where needed, e.g. "-", "m," or "no"
+func (t itemsTable[V]) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, code := range t.codes {
+		var key, value yaml.Node
+		if err := key.Encode(code); err != nil {
+			return nil, err
+		}
+		if err := value.Encode(t.values[code]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &key, &value)
+	}
+	return node, nil
+}
+
+// encodeTOML returns the body of the table as it appears under its [[items]] header, one code after another
+// Each code is encoded on its own as the only key of an items table, so keys are quoted and nested
+// tables named as the encoder does for the whole template
+func (t itemsTable[V]) encodeTOML(newEncoder func(io.Writer) *toml.Encoder) (string, error) {
+	var body strings.Builder
+	for _, code := range t.codes {
+		var buf bytes.Buffer
+		doc := struct {
+			Items []map[string]V `toml:"items"`
+		}{Items: []map[string]V{{code: t.values[code]}}}
+		if err := newEncoder(&buf).Encode(doc); err != nil {
+			return "", err
+		}
+		// Drop the [[items]] header, the template provides it
+		_, fragment, _ := strings.Cut(buf.String(), "\n")
+		fragment = strings.TrimRight(fragment, "\n")
+		if body.Len() > 0 {
+			body.WriteString("\n")
+			// Nested tables, as written with --with-hints, are separated by a blank line
+			if strings.HasPrefix(strings.TrimSpace(fragment), "[") {
+				body.WriteString("\n")
+			}
+		}
+		body.WriteString(fragment)
+	}
+	return body.String(), nil
+}
+
+// tomlItemsTable is an itemsTable of any value type, see encodeTOML
+type tomlItemsTable interface {
+	encodeTOML(newEncoder func(io.Writer) *toml.Encoder) (string, error)
+}
+
+// itemsPlaceholder is the key standing in for the codes of items table i while the rest of the template is encoded
+func itemsPlaceholder(i int) string {
+	return fmt.Sprintf("__yu_tool_items_%d__", i)
+}
+
+// addItemHints pairs each item word with its hint from the items_meta's hint_category file
+// The hint file has a "word hint" line per word, see readHintFile, and is looked up like the category files of generateItemsFromMeta;
+// words without a hint, or items without a hint_category, get an empty hint
+func addItemHints(items []map[string][]string, itemsMeta []TemplateItemsMeta, methodNameSuffix string, config ExportConfig) ([]map[string][]ItemCandidate, error) {
+	itemsConfig := config
+	if config.ItemsDir != "" {
+		itemsConfig.TargetPath = config.ItemsDir
+	}
+
+	hinted := make([]map[string][]ItemCandidate, len(items))
+	for i, item := range items {
+		hints := make(map[string]string)
+		if category := itemsMeta[i].HintCategory; category != "" {
+			suffixes := []string{""}
+			if methodNameSuffix != "" {
+				suffixes = []string{methodNameSuffix, ""}
+			}
+			for _, suffix := range suffixes {
+				hintPath, err := resolveOutputPath(itemsConfig, category, suffix)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := os.Stat(hintPath); os.IsNotExist(err) {
+					continue
+				}
+				if err := readHintFile(hintPath, config.Separator, hints); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+
+		hinted[i] = make(map[string][]ItemCandidate, len(item))
+		for code, words := range item {
+			for _, word := range words {
+				hinted[i][code] = append(hinted[i][code], ItemCandidate{Word: word, Hint: hints[word]})
+			}
+		}
+	}
+	return hinted, nil
+}
+
+// readHintFile adds the hints of a "word hint" file such as roots_pinyin.txt to hints, keeping the first per word
+// The hint is everything after the first separator, so it may contain spaces
+func readHintFile(path, sep string, hints map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	isFirstLine := true
+	for scanner.Scan() {
+		word, hint, found := strings.Cut(trimLine(scanner.Text(), isFirstLine), sep)
+		isFirstLine = false
+		if !found || word == "" {
+			continue
+		}
+		if _, exists := hints[word]; !exists {
+			hints[word] = hint
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	return nil
+}
+
+// exportTemplateFromFile reads a template file, updates configversion, and writes to target
+func exportTemplateFromFile(templatePath, outputName, methodNameSuffix string, config ExportConfig) (TemplateStats, error) {
+	// Read and parse TOML template
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	var tmplMeta TemplateMeta
+	if err := toml.Unmarshal(content, &tmplMeta); err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to parse template file: %w", err)
+	}
+	if err := validateTemplateMeta(tmplMeta); err != nil {
+		return TemplateStats{}, fmt.Errorf("invalid template '%s':\n%w", templatePath, err)
+	}
+	if err := checkKeyBindings(tmplMeta.KeyBindings, templatePath, config); err != nil {
+		return TemplateStats{}, err
+	}
+
+	// Update configversion
+	newVersion, err := updateConfigVersion(tmplMeta.ConfigVersion, config.MonotonicSeq)
+	if err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to update configversion: %w", err)
+	}
+	slog.Info("updated config_version", "template", templatePath, "from", tmplMeta.ConfigVersion, "to", newVersion)
+	tmplMeta.ConfigVersion = newVersion
+
+	// Update original template file if --update flag is set
+	if config.Update {
+		if err := updateTemplateConfigVersion(templatePath, newVersion); err != nil {
+			return TemplateStats{}, fmt.Errorf("failed to update template file: %w", err)
+		}
+	}
+
+	// Generate Items from ItemsMeta
+	items, codeOrders, err := generateItemsFromMeta(tmplMeta.ItemsMeta, methodNameSuffix, config)
+	if err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to generate items: %w", err)
+	}
+	codeOrders = sortItemCodes(items, codeOrders, config.SortItemsBy)
+	templateItems := orderedItems(items, codeOrders)
+	if config.WithHints {
+		hinted, err := addItemHints(items, tmplMeta.ItemsMeta, methodNameSuffix, config)
+		if err != nil {
+			return TemplateStats{}, fmt.Errorf("failed to read item hints: %w", err)
+		}
+		templateItems = orderedItems(hinted, codeOrders)
+	}
+
+	// Convert to Template for output (ItemsMeta will be excluded)
+	tmpl := Template{
+		Name:          tmplMeta.Name,
+		Version:       config.Version,
+		ConfigVersion: tmplMeta.ConfigVersion,
+		Fonts:         tmplMeta.Fonts,
+		KeyBindings:   tmplMeta.KeyBindings,
+		Items:         templateItems,
+		Tabs:          tmplMeta.Tabs,
+		Text:          tmplMeta.Text,
+		Help:          tmplMeta.Help,
+	}
+
+	// Use template's Version if config.Version is empty
+	if tmpl.Version == "" {
+		tmpl.Version = tmplMeta.Version
+	}
+
+	// Write to output TOML or YAML file with proper formatting
+	outputPath := templateOutputPath(config, outputName)
+	if config.ExtractHelp && len(tmpl.Help) > 0 {
+		helpPath := helpOutputPath(outputPath)
+		help := strings.Join(tmpl.Help, "\n") + "\n"
+		if err := writeFileAtomic(helpPath, []byte(help), config); err != nil {
+			return TemplateStats{}, fmt.Errorf("failed to write help file: %w", err)
+		}
+		config.progress.Add(1)
+		slog.Info("wrote help", "file", helpPath)
+		tmpl.Help = []string{filepath.Base(helpPath)}
+	}
+	outputData, err := marshalTemplate(tmpl, config.TemplateFormat, config.Indent)
+	if err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	if err := writeFileAtomic(outputPath, outputData, config); err != nil {
+		return TemplateStats{}, fmt.Errorf("failed to write output file: %w", err)
+	}
+	config.progress.Add(1)
+	slog.Info("wrote template", "file", outputPath, "items", len(items))
+
+	tmplStats := TemplateStats{File: outputPath}
+	for _, item := range items {
+		tmplStats.Items = append(tmplStats.Items, len(item))
+	}
+	return tmplStats, nil
+}
+
+// validateTemplateMeta checks the fields every template needs and reports all violations together
+func validateTemplateMeta(meta TemplateMeta) error {
+	var errs []error
+	if meta.Name == "" {
+		errs = append(errs, errors.New("name is empty"))
+	}
+	for i, font := range meta.Fonts {
+		if font.Name == "" {
+			errs = append(errs, fmt.Errorf("fonts[%d].name is empty", i))
+		}
+		if font.Type == "" {
+			errs = append(errs, fmt.Errorf("fonts[%d].type is empty", i))
+		}
+	}
+	for i, binding := range meta.KeyBindings {
+		if binding.Key == "" {
+			errs = append(errs, fmt.Errorf("key_bindings[%d].key is empty", i))
+		}
+	}
+	for i, tab := range meta.Tabs {
+		if tab.Label == "" {
+			errs = append(errs, fmt.Errorf("tabs[%d].label is empty", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// templateOutputPath returns where a template named like "yuling.toml" is written,
+// adding the release version and the extension of the selected format
+func templateOutputPath(config ExportConfig, outputName string) string {
+	ext := ".toml"
+	if config.TemplateFormat == "yaml" {
+		ext = ".yaml"
+	}
+	baseName := strings.TrimSuffix(outputName, ".toml")
+	if config.Version != "" {
+		baseName += "_" + config.Version
+	}
+	return filepath.Join(config.TargetPath, baseName+ext)
+}
+
+// helpOutputPath returns where --extract-help writes the help of the template written to templatePath,
+// e.g. "yuling_3.9.0_help.html" next to "yuling_3.9.0.toml"
+func helpOutputPath(templatePath string) string {
+	return strings.TrimSuffix(templatePath, filepath.Ext(templatePath)) + "_help.html"
+}
+
+// parseIndent converts an --indent value into the indent string: a number of spaces,
+// "tab", or "0" for no indentation. An empty value keeps the encoder's default
+func parseIndent(indent, format string) (string, error) {
+	var symbol string
+	switch indent {
+	case "":
+		return "", nil
+	case "tab":
+		symbol = "\t"
+	default:
+		width, err := strconv.Atoi(indent)
+		if err != nil || width < 0 {
+			return "", fmt.Errorf("invalid indent: %s (expected a number of spaces, tab or 0)", indent)
+		}
+		symbol = strings.Repeat(" ", width)
+	}
+	if format == "yaml" && (len(symbol) < 2 || len(symbol) > 9 || symbol[0] == '\t') {
+		return "", fmt.Errorf("invalid indent for yaml output: %s (expected 2 to 9 spaces)", indent)
+	}
+	return symbol, nil
+}
+
+// marshalTemplate encodes tmpl as TOML or YAML using the --indent setting
+func marshalTemplate(tmpl Template, format, indent string) ([]byte, error) {
+	symbol, err := parseIndent(indent, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if format == "yaml" {
+		enc := yaml.NewEncoder(&buf)
+		if symbol != "" {
+			enc.SetIndent(len(symbol))
+		}
+		if err := enc.Encode(tmpl); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	newEncoder := func(w io.Writer) *toml.Encoder {
+		enc := toml.NewEncoder(w)
+		if indent != "" {
+			enc.SetIndentSymbol(symbol)
+			enc.SetIndentTables(symbol != "")
+		}
+		return enc
+	}
+
+	// Items tables are encoded as placeholder keys, then replaced by their codes in order, see encodeTOML
+	tables, _ := tmpl.Items.([]any)
+	placeholders := make([]map[string]string, len(tables))
+	for i := range tables {
+		placeholders[i] = map[string]string{itemsPlaceholder(i): ""}
+	}
+	if tables != nil {
+		tmpl.Items = placeholders
+	}
+	if err := newEncoder(&buf).Encode(tmpl); err != nil {
+		return nil, err
+	}
+	out := buf.String()
+	for i, table := range tables {
+		ordered, ok := table.(tomlItemsTable)
+		if !ok {
+			return nil, fmt.Errorf("unexpected items table type %T", table)
+		}
+		body, err := ordered.encodeTOML(newEncoder)
+		if err != nil {
+			return nil, err
+		}
+		placeholder := regexp.MustCompile(`(?m)^[ \t]*` + itemsPlaceholder(i) + ` = .*\n`)
+		if body != "" {
+			body += "\n"
+		}
+		loc := placeholder.FindStringIndex(out)
+		if loc == nil {
+			return nil, fmt.Errorf("items table %d missing from encoded template", i)
+		}
+		out = out[:loc[0]] + body + out[loc[1]:]
+	}
+	return []byte(out), nil
+}
+
+// updateConfigVersion updates the configversion based on current date
+// configversion format: "YYYY.M.D-seq" (e.g., "2026.1.29-1")
+// The sequence restarts at 1 on a new date unless monotonic is set, which carries it on across dates
+func updateConfigVersion(current string, monotonic bool) (string, error) {
+	// Parse current configversion
+	var datePart string
+	var seq int
+	parts := strings.Split(current, "-")
+	if len(parts) >= 2 {
+		datePart = parts[0]
+		if _, err := fmt.Sscanf(parts[1], "%d", &seq); err != nil {
+			seq = 0
+		}
+	} else {
+		datePart = current
+		seq = 0
+	}
+
+	// Get current date in the same format
+	now := time.Now()
+	currentDate := fmt.Sprintf("%d.%d.%d", now.Year(), int(now.Month()), now.Day())
+
+	// Compare dates and update sequence
+	if datePart == currentDate || monotonic {
+		seq++
+	} else {
+		seq = 1
+	}
+
+	return fmt.Sprintf("%s-%d", currentDate, seq), nil
+}
+
+// updateTemplateConfigVersion updates the configversion in the original template TOML file
+func updateTemplateConfigVersion(templatePath, newVersion string) error {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	// Replace config_version value using regex (TOML format)
+	configVersionRegex := regexp.MustCompile(`(?m)^config_version\s*=\s*["'].*["']`)
+	newContent := configVersionRegex.ReplaceAllString(string(content), fmt.Sprintf(`config_version = "%s"`, newVersion))
+
+	if err := os.WriteFile(templatePath, []byte(newContent), 0644); err != nil {
+		return fmt.Errorf("failed to write template file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,541 @@
+package yuexport
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// writeTestZip creates a zip at path holding files, keyed by entry name
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractSchemaWrappedZip(t *testing.T) {
+	const customYAML = "patch:\n  schema_list:\n    - schema: yuling\n"
+	tests := []struct {
+		name   string
+		prefix string // directory wrapping every entry of the zip
+	}{
+		{"unwrapped", ""},
+		{"wrapped", "yuling-3.9.0/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "yuling_3.9.0.zip")
+			writeTestZip(t, src, map[string]string{
+				tt.prefix + "schema/default.custom.yaml":          customYAML,
+				tt.prefix + "schema/yuhao/yuling.quick.dict.yaml": "---\n...\n土\tg\n",
+			})
+
+			dest := filepath.Join(dir, "out")
+			root, methodName, err := extractSchema(context.Background(), src, dest, schemaConfigFile, "", ExtractOptions{Quiet: true})
+			if err != nil {
+				t.Fatalf("extractSchema: %v", err)
+			}
+			if methodName != "yuling" {
+				t.Errorf("methodName = %q, want %q", methodName, "yuling")
+			}
+			if want := filepath.Join(dest, filepath.FromSlash(tt.prefix)); filepath.Clean(root) != filepath.Clean(want) {
+				t.Errorf("root = %q, want %q", root, want)
+			}
+			if _, err := os.Stat(filepath.Join(root, "schema", "yuhao", "yuling.quick.dict.yaml")); err != nil {
+				t.Errorf("dict not found under root: %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeZipEntryName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "schema/yuhao/a.txt", want: "schema/yuhao/a.txt"},
+		{name: "/etc/passwd", want: "etc/passwd"},
+		{name: "//abs//path", want: "abs/path"},
+		{name: `schema\yuhao\a.txt`, want: "schema/yuhao/a.txt"},
+		{name: `C:\Windows\evil`, want: "Windows/evil"},
+		{name: "foo/../bar", want: "bar"},
+		{name: "../evil", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: `foo\..\..\bar`, wantErr: true},
+		{name: "/../../etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeZipEntryName(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeZipEntryName(%q) = %q, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeZipEntryName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExtractZipUnsafeNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    string // path of the extracted file relative to the destination, "" when rejected
+		wantErr bool
+	}{
+		{name: "parent", entry: "../evil", wantErr: true},
+		{name: "absolute", entry: "/etc/passwd", want: "etc/passwd"},
+		{name: "backslash parent", entry: `foo\..\..\bar`, wantErr: true},
+		{name: "backslash", entry: `schema\yuhao\a.txt`, want: "schema/yuhao/a.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "test.zip")
+			writeTestZip(t, src, map[string]string{tt.entry: "content"})
+
+			dest := filepath.Join(dir, "out")
+			err := extractZipToDir(context.Background(), src, dest, ExtractOptions{Quiet: true})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extracting %q succeeded, want error", tt.entry)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+					t.Errorf("extracting %q wrote outside the destination", tt.entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extracting %q: %v", tt.entry, err)
+			}
+			data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(tt.want)))
+			if err != nil || string(data) != "content" {
+				t.Errorf("extracting %q: got %q, %v at %s", tt.entry, data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractVersionFromFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"name_1.0.zip", "1.0"},
+		{"name_variant_1.0.zip", "1.0"},
+		{"yuhao_joined_3.2.zip", "3.2"},
+		{"dir/yuling_3.9.0.zip", "3.9.0"},
+		{"name_1.x.zip", "1.x"},
+		{"name_variant_1.x.zip", "variant"},
+		{"name.zip", ""},
+	}
+	for _, tt := range tests {
+		if got := extractVersionFromFilename(tt.filename); got != tt.want {
+			t.Errorf("extractVersionFromFilename(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestFindSuffixedFilesSkipsDecoys(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"yuling.quick.dict.yaml",
+		"yuling_tc.quick.dict.yaml",
+		"yuling_old.quick.dict.yaml~",
+		"yuling_bak.quick.dict.yaml.bak",
+		".#yuling_lock.quick.dict.yaml",
+		".yuling_hidden.quick.dict.yaml",
+		"yuling_tc.pop.dict.yaml",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := findSuffixedFiles(dir, "yuling", "quick")
+	want := map[string]string{"tc": filepath.Join(dir, "yuling_tc.quick.dict.yaml")}
+	if len(got) != len(want) || got["tc"] != want["tc"] {
+		t.Errorf("findSuffixedFiles = %v, want %v", got, want)
+	}
+}
+
+func TestIsIgnoredFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"yuling_tc.quick.dict.yaml", false},
+		{"yuling_tc.quick.dict.yaml~", true},
+		{"yuling_tc.quick.dict.yaml.bak", true},
+		{".#yuling_tc.quick.dict.yaml", true},
+		{".DS_Store", true},
+	}
+	for _, tt := range tests {
+		if got := isIgnoredFile(tt.name); got != tt.want {
+			t.Errorf("isIgnoredFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWordNFD(t *testing.T) {
+	const decomposed, composed = "cafe\u0301", "caf\u00e9" // with a combining acute accent, and precomposed
+	tests := []struct {
+		form string
+		in   string
+		want string
+	}{
+		{"nfc", decomposed, composed},
+		{"nfc", composed, composed},
+		{"nfd", composed, decomposed},
+		{"none", decomposed, decomposed},
+		{"", decomposed, decomposed},
+	}
+	for _, tt := range tests {
+		if got := normalizeWord(tt.in, ExportConfig{UnicodeNormalize: tt.form}); got != tt.want {
+			t.Errorf("normalizeWord(%+q) with %q = %+q, want %+q", tt.in, tt.form, got, tt.want)
+		}
+	}
+}
+
+func TestReadRootsFromCSVNormalizesNFD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roots.csv")
+	// Two spellings of the same root: decomposed and precomposed A with ring above
+	if err := os.WriteFile(path, []byte("font,ma\nA\u030a,aa\n\u00c5,ab\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := ExportConfig{CSVDelimiter: ",", CommentChar: "#", UnicodeNormalize: "nfc"}
+	roots, _, err := readRootsFromCSV(context.Background(), path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0][1] != "\u00c5" || roots[1][1] != "\u00c5" {
+		t.Errorf("roots = %+q, want both words as %+q", roots, "\u00c5")
+	}
+}
+
+func TestMarshalTemplateItemCodes(t *testing.T) {
+	// Codes that are not valid struct tag names or TOML bare keys, in an order that is not lexical
+	codes := []string{"m,", "-", "a-b", "no", "ab"}
+	items := []map[string][]string{{"m,": {"一"}, "-": {"二"}, "a-b": {"三"}, "no": {"四"}, "ab": {"五"}}}
+	hinted := []map[string][]ItemCandidate{{
+		"m,": {{Word: "一", Hint: "yi"}}, "-": {{Word: "二"}}, "a-b": {{Word: "三"}}, "no": {{Word: "四"}}, "ab": {{Word: "五"}},
+	}}
+	want := map[string]string{"m,": "一", "-": "二", "a-b": "三", "no": "四", "ab": "五"}
+
+	for _, format := range []string{"toml", "yaml"} {
+		for _, withHints := range []bool{false, true} {
+			tmpl := Template{Name: "test", Items: orderedItems(items, [][]string{codes})}
+			if withHints {
+				tmpl.Items = orderedItems(hinted, [][]string{codes})
+			}
+			data, err := marshalTemplate(tmpl, format, "")
+			if err != nil {
+				t.Fatalf("%s (hints %v): marshalTemplate: %v", format, withHints, err)
+			}
+
+			var decoded struct {
+				Items []map[string][]any `toml:"items" yaml:"items"`
+			}
+			if format == "yaml" {
+				err = yaml.Unmarshal(data, &decoded)
+			} else {
+				err = toml.Unmarshal(data, &decoded)
+			}
+			if err != nil {
+				t.Fatalf("%s (hints %v): decoding output: %v\n%s", format, withHints, err, data)
+			}
+			if len(decoded.Items) != 1 || len(decoded.Items[0]) != len(want) {
+				t.Fatalf("%s (hints %v): items = %v, want codes %v", format, withHints, decoded.Items, codes)
+			}
+			for code, word := range want {
+				values := decoded.Items[0][code]
+				if len(values) != 1 {
+					t.Errorf("%s (hints %v): code %q = %v, want [%s]", format, withHints, code, values, word)
+					continue
+				}
+				got := values[0]
+				if candidate, ok := got.(map[string]any); ok {
+					got = candidate["word"]
+				}
+				if got != word {
+					t.Errorf("%s (hints %v): code %q = %v, want %s", format, withHints, code, got, word)
+				}
+			}
+
+			// Codes keep the given order in the output
+			last := -1
+			for _, code := range codes {
+				index := strings.Index(string(data), want[code])
+				if index < last {
+					t.Errorf("%s (hints %v): code %q out of order:\n%s", format, withHints, code, data)
+				}
+				last = index
+			}
+		}
+	}
+}
+
+func TestOutputManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.txt")
+	output := filepath.Join(dir, "quick", "quick_chars.txt")
+	digest, other := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	manifest, err := readOutputManifest(path)
+	if err != nil {
+		t.Fatalf("reading a missing manifest: %v", err)
+	}
+	if known, _ := manifest.unchanged(output, digest); known {
+		t.Errorf("output known to an empty manifest")
+	}
+	if err := manifest.write(ExportConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err = readOutputManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if known, same := manifest.unchanged(output, digest); !known || !same {
+		t.Errorf("unchanged(same digest) = %v, %v, want true, true", known, same)
+	}
+	if known, same := manifest.unchanged(output, other); !known || same {
+		t.Errorf("unchanged(other digest) = %v, %v, want true, false", known, same)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != digest+"  quick/quick_chars.txt\n" {
+		t.Errorf("manifest = %q, %v", data, err)
+	}
+}
+
+func TestCleanOutputsOnlySelectedStages(t *testing.T) {
+	dir := t.TempDir()
+	rootPath := filepath.Join(dir, "roots.csv")
+	target := filepath.Join(dir, "out")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rootPath, []byte("土,ga\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{"roots.txt", "roots_tc.txt", "quick_words.txt", "pop_chars.txt", "rejected.txt", "yuling.toml", "notes.txt"}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(target, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := ExportConfig{TargetPath: target, RootPath: rootPath, MethodName: "yuling", Only: []string{"root"}}
+	if err := cleanOutputs(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		_, err := os.Stat(filepath.Join(target, name))
+		if removed := err != nil; removed != (name == "roots_tc.txt") {
+			t.Errorf("%s removed = %v", name, removed)
+		}
+	}
+}
+
+func TestReadDictEntriesColumnOrders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		lines  string
+		want   dictColumns
+	}{
+		{"text first", "columns: [text, code, weight]", "土\tga\n土木\tgamu\t10\n", dictColumns{text: 0, code: 1, weight: 2}},
+		{"code first", "columns: [code, text, weight]", "ga\t土\ngamu\t土木\t10\n", dictColumns{text: 1, code: 0, weight: 2}},
+		{"code first without weight", "columns: [code, text]", "ga\t土\ngamu\t土木\n", dictColumns{text: 1, code: 0, weight: -1}},
+		{"default", "name: yuling", "土\tga\n土木\tgamu\t10\n", defaultDictColumns},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cols := parseDictColumns(tt.header+"\n", "test"); cols != tt.want {
+				t.Errorf("parseDictColumns = %+v, want %+v", cols, tt.want)
+			}
+
+			path := filepath.Join(t.TempDir(), "yuling.quick.dict.yaml")
+			if err := os.WriteFile(path, []byte("---\n"+tt.header+"\n...\n"+tt.lines), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, strict := range []bool{false, true} {
+				config := ExportConfig{Strict: strict, rejected: &rejectedLines{}}
+				words, chars, err := readDictEntries(context.Background(), path, "quick", config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(chars) != 1 || chars[0][0] != "ga" || chars[0][1] != "土" {
+					t.Errorf("strict %v: chars = %q, want [ga 土]", strict, chars)
+				}
+				if len(words) != 1 || words[0][0] != "gamu" || words[0][1] != "土木" {
+					t.Errorf("strict %v: words = %q, want [gamu 土木]", strict, words)
+				}
+				if len(config.rejected.lines) != 0 {
+					t.Errorf("strict %v: rejected %q", strict, config.rejected.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestReadRootsFromCSVQuotedFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roots.csv")
+	content := "font,ma\n" +
+		"\"土\",ga\n" + // quoted
+		" \"木\", mu\n" + // quoted after a space, read as a bare field by encoding/csv
+		"水,sh\n" + // unquoted
+		"\"a,b\",ab\n" + // quoted delimiter
+		"\"\"\"\",qt\n" // escaped quote
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := ExportConfig{CSVDelimiter: ",", CommentChar: "#"}
+	roots, _, err := readRootsFromCSV(context.Background(), path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"ga": "土", "mu": "木", "sh": "水", "ab": "a,b", "qt": `"`}
+	if len(roots) != len(want) {
+		t.Fatalf("roots = %q, want %d roots", roots, len(want))
+	}
+	for _, root := range roots {
+		if want[root[0]] != root[1] {
+			t.Errorf("root of %q = %q, want %q", root[0], root[1], want[root[0]])
+		}
+	}
+}
+
+func TestTrimCSVField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"土", "土"},
+		{` "土"`, "土"},
+		{` "土" `, "土"},
+		{` "a""b"`, `a"b`},
+		{`"`, `"`},
+		{" mu ", "mu"},
+	}
+	for _, tt := range tests {
+		if got := trimCSVField(tt.field); got != tt.want {
+			t.Errorf("trimCSVField(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestFreqReportCountsWordsBeforeDeduplication(t *testing.T) {
+	config := ExportConfig{TargetPath: t.TempDir(), Separator: " "}
+	words := []DictEntry{{"gamu", "土木"}, {"gamu", "土目"}, {"gamu", "土牧"}, {"gamu", "土木"}, {"mu", "木头"}}
+	stats, err := writeWordsAndChars("quick", "", words, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[0].Entries != 2 {
+		t.Fatalf("quick_words entries = %d, want 2 after de-duplication by code", stats[0].Entries)
+	}
+
+	report := buildFreqReport(stats[:1])
+	freq := report.Categories[0]
+	if freq.Codes != 2 || freq.Words != 4 {
+		t.Errorf("codes, words = %d, %d, want 2, 4", freq.Codes, freq.Words)
+	}
+	if want := map[int]int{1: 1, 3: 1}; len(freq.WordsPerCode) != len(want) || freq.WordsPerCode[1] != 1 || freq.WordsPerCode[3] != 1 {
+		t.Errorf("words per code = %v, want %v", freq.WordsPerCode, want)
+	}
+	if want := map[int]int{2: 1, 4: 1}; len(freq.CodeLengths) != len(want) || freq.CodeLengths[2] != 1 || freq.CodeLengths[4] != 1 {
+		t.Errorf("code lengths = %v, want %v", freq.CodeLengths, want)
+	}
+}
+
+func TestOtherSchemas(t *testing.T) {
+	names := []string{"yuling", "yuling_tc", "sijiao", "yulingx"}
+	got := otherSchemas(names, "yuling")
+	if want := []string{"sijiao", "yulingx"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("otherSchemas = %v, want %v", got, want)
+	}
+	if got := otherSchemas([]string{"yuling", "yuling_tc"}, "yuling"); len(got) != 0 {
+		t.Errorf("otherSchemas of variants only = %v, want none", got)
+	}
+}
+
+func TestMarshalTemplateHelpUnescaped(t *testing.T) {
+	const help = "<b>x</b> & y"
+	for _, format := range []string{"toml", "yaml"} {
+		data, err := marshalTemplate(Template{Name: "test", Help: []string{help}}, format, "")
+		if err != nil {
+			t.Fatalf("%s: marshalTemplate: %v", format, err)
+		}
+		if !strings.Contains(string(data), help) {
+			t.Errorf("%s: help is not written literally:\n%s", format, data)
+		}
+
+		var decoded struct {
+			Help []string `toml:"help" yaml:"help"`
+		}
+		if format == "yaml" {
+			err = yaml.Unmarshal(data, &decoded)
+		} else {
+			err = toml.Unmarshal(data, &decoded)
+		}
+		if err != nil || len(decoded.Help) != 1 || decoded.Help[0] != help {
+			t.Errorf("%s: decoded help = %q, %v, want %q", format, decoded.Help, err, help)
+		}
+	}
+}
+
+func TestSortItemCodesSortsWords(t *testing.T) {
+	newItems := func() []map[string][]string {
+		return []map[string][]string{{"gb": {"木", "土"}, "a": {"水", "火", "日"}}}
+	}
+	codeOrders := [][]string{{"gb", "a"}}
+
+	items := newItems()
+	codes := sortItemCodes(items, codeOrders, "code")
+	if got := strings.Join(codes[0], ","); got != "a,gb" {
+		t.Errorf("codes = %s, want a,gb", got)
+	}
+	if got := strings.Join(items[0]["a"], ""); got != "日水火" {
+		t.Errorf("words of a = %s, want 日水火", got)
+	}
+	if got := strings.Join(items[0]["gb"], ""); got != "土木" {
+		t.Errorf("words of gb = %s, want 土木", got)
+	}
+
+	items = newItems()
+	codes = sortItemCodes(items, codeOrders, "source")
+	if got := strings.Join(codes[0], ","); got != "gb,a" {
+		t.Errorf("source codes = %s, want gb,a", got)
+	}
+	if got := strings.Join(items[0]["a"], ""); got != "水火日" {
+		t.Errorf("source words of a = %s, want 水火日", got)
+	}
+}
@@ -0,0 +1,108 @@
+package yuexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CategoryFreq aggregates the entries parsed for one output file, including words the file drops on code collisions
+type CategoryFreq struct {
+	Category     string      `json:"category"`
+	Codes        int         `json:"codes"`
+	Words        int         `json:"words"`
+	WordsPerCode map[int]int `json:"words_per_code"` // number of codes by how many words they map to
+	CodeLengths  map[int]int `json:"code_lengths"`   // number of codes by code length
+}
+
+// FreqReport aggregates the entries of every exported text file, keyed as in a bundle, see bundleKey
+type FreqReport struct {
+	Categories []CategoryFreq `json:"categories"`
+}
+
+// buildFreqReport counts the distinct words of each code in the parsed entries of files, before the outputs
+// keep one word per code, sorted by bundleKey
+func buildFreqReport(files []FileStats) FreqReport {
+	report := FreqReport{Categories: make([]CategoryFreq, 0, len(files))}
+	for _, file := range files {
+		entries := file.parsed
+		if entries == nil {
+			entries = file.written
+		}
+		words := make(map[string]int)
+		seen := make(map[[2]string]bool)
+		for _, entry := range entries {
+			if pair := [2]string{entry[0], entry[1]}; !seen[pair] {
+				seen[pair] = true
+				words[entry[0]]++
+			}
+		}
+		freq := CategoryFreq{
+			Category:     bundleKey(file),
+			Codes:        len(words),
+			Words:        len(seen),
+			WordsPerCode: make(map[int]int),
+			CodeLengths:  make(map[int]int),
+		}
+		for code, n := range words {
+			freq.WordsPerCode[n]++
+			freq.CodeLengths[len(code)]++
+		}
+		report.Categories = append(report.Categories, freq)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+	return report
+}
+
+// formatFreqReport renders report as indented JSON, or as text with one section per category
+func formatFreqReport(report FreqReport, asJSON bool) ([]byte, error) {
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode frequency report: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	var b strings.Builder
+	for i, freq := range report.Categories {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\ncodes\t%d\nwords\t%d\n", freq.Category, freq.Codes, freq.Words)
+		writeHistogram(&b, "words per code", freq.WordsPerCode)
+		writeHistogram(&b, "code lengths", freq.CodeLengths)
+	}
+	return []byte(b.String()), nil
+}
+
+// writeHistogram writes the title and one "key<tab>count" line per key of counts, in key order
+func writeHistogram(b *strings.Builder, title string, counts map[int]int) {
+	keys := make([]int, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	fmt.Fprintf(b, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(b, "  %d\t%d\n", key, counts[key])
+	}
+}
+
+// writeFreqReport writes the frequency report of files to path, as JSON if path ends with .json and as text otherwise
+func writeFreqReport(path string, files []FileStats, config ExportConfig) error {
+	data, err := formatFreqReport(buildFreqReport(files), strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return err
+	}
+	if err := writeTextFileAtomic(path, data, config); err != nil {
+		return err
+	}
+	slog.Info("wrote frequency report", "file", path, "outputs", len(files))
+	return nil
+}
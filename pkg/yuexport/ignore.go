@@ -0,0 +1,90 @@
+package yuexport
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ignoreRule drops every entry of a code, or with word set only that code-word pair
+type ignoreRule struct {
+	code    string
+	word    string
+	removed int
+}
+
+// ignoreList holds the rules of an --ignore-file
+// A nil *ignoreList matches nothing
+type ignoreList struct {
+	rules []*ignoreRule
+	codes map[string]*ignoreRule
+	pairs map[[2]string]*ignoreRule
+}
+
+// readIgnoreFile reads "code" or "code<tab>word" lines; blank lines and "#" comments are skipped
+// Codes and words are normalized like the dict entries they are matched against
+func readIgnoreFile(path string, config ExportConfig) (*ignoreList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ignore file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	list := &ignoreList{codes: make(map[string]*ignoreRule), pairs: make(map[[2]string]*ignoreRule)}
+	scanner := bufio.NewScanner(file)
+	isFirstLine := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(trimLine(scanner.Text(), isFirstLine))
+		isFirstLine = false
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, word, _ := strings.Cut(line, "\t")
+		rule := &ignoreRule{
+			code: normalizeCodeCase(strings.TrimSpace(code), config.CodeCase),
+			word: normalizeWord(strings.TrimSpace(word), config),
+		}
+		if rule.word == "" {
+			list.codes[rule.code] = rule
+		} else {
+			list.pairs[[2]string{rule.code, rule.word}] = rule
+		}
+		list.rules = append(list.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading ignore file '%s': %w", path, err)
+	}
+	return list, nil
+}
+
+// matches reports whether an entry is ignored, counting it against the matching rule
+func (l *ignoreList) matches(code, word string) bool {
+	if l == nil {
+		return false
+	}
+	rule, found := l.codes[code]
+	if !found {
+		rule, found = l.pairs[[2]string{code, word}]
+	}
+	if !found {
+		return false
+	}
+	rule.removed++
+	return true
+}
+
+// logRemoved logs how many entries each rule removed
+func (l *ignoreList) logRemoved() {
+	if l == nil {
+		return
+	}
+	for _, rule := range l.rules {
+		if rule.word == "" {
+			slog.Info("ignored entries", "code", rule.code, "count", rule.removed)
+		} else {
+			slog.Info("ignored entries", "code", rule.code, "word", rule.word, "count", rule.removed)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package yuexport
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// outputManifest holds the digests of the previous export, read from ExportConfig.PrevManifest,
+// and collects those of this export to replace them
+// A nil *outputManifest ignores all calls
+type outputManifest struct {
+	path     string
+	previous map[string]string
+	current  map[string]string
+}
+
+// readOutputManifest reads the manifest at path, in sha256sum format with paths relative to its directory
+// A missing manifest is empty, as on the first incremental run
+func readOutputManifest(path string) (*outputManifest, error) {
+	manifest := &outputManifest{path: path, previous: map[string]string{}, current: map[string]string{}}
+	sums, err := readChecksums(path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("no previous manifest, writing every output", "file", path)
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest.previous = sums
+	return manifest, nil
+}
+
+// key returns how output is named in the manifest: relative to the manifest's directory, with slashes
+func (m *outputManifest) key(output string) string {
+	dir, err := filepath.Abs(filepath.Dir(m.path))
+	if err != nil {
+		return filepath.ToSlash(output)
+	}
+	abs, err := filepath.Abs(output)
+	if err != nil {
+		return filepath.ToSlash(output)
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// unchanged records digest for output and reports whether the previous manifest has the same digest for it
+func (m *outputManifest) unchanged(output, digest string) (known, same bool) {
+	if m == nil {
+		return false, false
+	}
+	key := m.key(output)
+	m.current[key] = digest
+	previous, known := m.previous[key]
+	return known, previous == digest
+}
+
+// write replaces the manifest file with the digests of this export, sorted by path
+func (m *outputManifest) write(config ExportConfig) error {
+	if m == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(m.current))
+	for key := range m.current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s  %s\n", m.current[key], key)
+	}
+
+	// The manifest itself is always written, not compared or recorded
+	config.Incremental = false
+	config.manifest = nil
+	if err := writeFileAtomic(m.path, []byte(b.String()), config); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	slog.Info("wrote manifest", "file", m.path, "outputs", len(keys))
+	return nil
+}
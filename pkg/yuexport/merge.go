@@ -0,0 +1,37 @@
+package yuexport
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// MergeFiles combines exported "code word" text files such as quick_words.txt into outputPath
+// Entries are sorted by code; on a code collision the word from the earlier input wins,
+// or with multiWord all words of the code are kept on one line
+// sep is the separator used by the inputs and written to the output
+func MergeFiles(inputs []string, outputPath, sep string, multiWord bool) (FileStats, error) {
+	if len(inputs) == 0 {
+		return FileStats{}, errors.New("no input files to merge")
+	}
+
+	var entries []DictEntry
+	for _, input := range inputs {
+		if category, _, _ := parseOutputFileName(filepath.Base(input)); category == "roots" || category == "roots_pinyin" {
+			return FileStats{}, fmt.Errorf("cannot merge '%s': roots files are not in code-word format", input)
+		}
+		inputEntries, err := readCodeWordFile(input, false, sep, false)
+		if err != nil {
+			return FileStats{}, fmt.Errorf("failed to read '%s': %w", input, err)
+		}
+		entries = append(entries, inputEntries...)
+	}
+
+	config := ExportConfig{Separator: sep, MultiWord: multiWord}
+	written, err := writeCodeWordPairs(outputPath, entries, config)
+	if err != nil {
+		return FileStats{}, err
+	}
+	category, suffix, _ := parseOutputFileName(filepath.Base(outputPath))
+	return FileStats{File: outputPath, Category: category, Suffix: suffix, Entries: len(written), written: written}, nil
+}
@@ -0,0 +1,221 @@
+package yuexport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+)
+
+// writeRetryDelay is the wait before the first retry of a transient write error, doubled for each further retry
+const writeRetryDelay = 100 * time.Millisecond
+
+// retryTransient runs op, retrying it up to retries times while it fails with a transient error
+// Other errors, such as permission denied or no space left, are returned at once
+func retryTransient(retries int, op func() error) error {
+	delay := writeRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt > retries || !isTransientError(err) {
+			return err
+		}
+		slog.Warn("retrying after transient error", "attempt", attempt, "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isTransientError reports whether err is worth retrying, as on a busy network mount
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EINTR)
+}
+
+// atomicFile is an output file written to path+".tmp" and renamed over path on Commit,
+// so readers never see a half-written file
+type atomicFile struct {
+	*os.File
+	path        string
+	retries     int
+	incremental bool
+	unchanged   *unchangedFiles
+	manifest    *outputManifest
+	committed   bool
+
+	// Set for text outputs by createTextAtomic when the line ending or final newline differ from the defaults
+	text         bool
+	lineEnding   string
+	finalNewline bool
+	pending      bool // a line end held back until more is written or Commit
+}
+
+// unchangedFiles collects the outputs that --incremental left untouched
+// A nil *unchangedFiles ignores all calls
+type unchangedFiles struct {
+	paths []string
+}
+
+func (u *unchangedFiles) add(path string) {
+	if u == nil {
+		return
+	}
+	u.paths = append(u.paths, path)
+}
+
+// createAtomic creates the temp file for path
+// Callers defer Abort, which removes the temp file unless Commit succeeded
+// Creating and, on Commit, renaming the file are retried up to config.WriteRetries times after transient errors
+func createAtomic(path string, config ExportConfig) (*atomicFile, error) {
+	var file *os.File
+	err := retryTransient(config.WriteRetries, func() error {
+		var err error
+		file, err = os.Create(path + ".tmp")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create '%s': %w", path, err)
+	}
+	return &atomicFile{
+		File:        file,
+		path:        path,
+		retries:     config.WriteRetries,
+		incremental: config.Incremental,
+		unchanged:   config.unchanged,
+		manifest:    config.manifest,
+	}, nil
+}
+
+// createTextAtomic is createAtomic for line-based text outputs, whose "\n" line ends are written
+// as config.LineEnding, and dropped after the last line with config.NoFinalNewline
+func createTextAtomic(path string, config ExportConfig) (*atomicFile, error) {
+	file, err := createAtomic(path, config)
+	if err != nil {
+		return nil, err
+	}
+	file.lineEnding = "\n"
+	if config.LineEnding == "crlf" {
+		file.lineEnding = "\r\n"
+	}
+	file.finalNewline = !config.NoFinalNewline
+	file.text = file.lineEnding != "\n" || !file.finalNewline
+	return file, nil
+}
+
+// Write writes p to the temp file, translating line ends for text outputs
+// The last line end is held back so that Commit can leave it out
+func (f *atomicFile) Write(p []byte) (int, error) {
+	if !f.text {
+		return f.File.Write(p)
+	}
+	var buf bytes.Buffer
+	if f.pending {
+		buf.WriteString(f.lineEnding)
+	}
+	data, pending := bytes.CutSuffix(p, []byte("\n"))
+	buf.Write(bytes.ReplaceAll(data, []byte("\n"), []byte(f.lineEnding)))
+	if _, err := f.File.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	f.pending = pending
+	return len(p), nil
+}
+
+// WriteString is Write for strings
+func (f *atomicFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// Commit flushes the temp file to disk and renames it over the target path
+// In incremental mode a target with the same content is kept as is, along with its modification time, see unchangedOutput
+func (f *atomicFile) Commit() error {
+	if f.pending && f.finalNewline {
+		if _, err := f.File.WriteString(f.lineEnding); err != nil {
+			return fmt.Errorf("failed to write to '%s': %w", f.Name(), err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync '%s': %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close '%s': %w", f.Name(), err)
+	}
+	if f.incremental && f.unchangedOutput() {
+		os.Remove(f.Name())
+		f.committed = true
+		f.unchanged.add(f.path)
+		slog.Debug("output unchanged, not rewritten", "file", f.path)
+		return nil
+	}
+	if err := retryTransient(f.retries, func() error { return os.Rename(f.Name(), f.path) }); err != nil {
+		return fmt.Errorf("failed to rename '%s' to '%s': %w", f.Name(), f.path, err)
+	}
+	f.committed = true
+	return nil
+}
+
+// Abort closes and removes the temp file; it does nothing after a successful Commit
+func (f *atomicFile) Abort() {
+	if f.committed {
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// unchangedOutput reports whether the committed temp file matches the output it replaces
+// With a previous manifest the output is unchanged when the manifest lists the same digest for it and it still exists;
+// without one the output on disk is hashed and compared
+func (f *atomicFile) unchangedOutput() bool {
+	if f.manifest == nil {
+		return sameContent(f.Name(), f.path)
+	}
+	digest, err := fileSHA256(f.Name())
+	if err != nil {
+		return false
+	}
+	if _, same := f.manifest.unchanged(f.path, digest); !same {
+		return false
+	}
+	_, err = os.Stat(f.path)
+	return err == nil
+}
+
+// sameContent reports whether the files at a and b both exist and have the same SHA-256 digest
+func sameContent(a, b string) bool {
+	hashA, err := fileSHA256(a)
+	if err != nil {
+		return false
+	}
+	hashB, err := fileSHA256(b)
+	return err == nil && hashA == hashB
+}
+
+// writeFileAtomic is os.WriteFile through an atomicFile
+func writeFileAtomic(path string, data []byte, config ExportConfig) error {
+	file, err := createAtomic(path, config)
+	if err != nil {
+		return err
+	}
+	return writeAndCommit(file, data)
+}
+
+// writeTextFileAtomic is writeFileAtomic for line-based text outputs, see createTextAtomic
+func writeTextFileAtomic(path string, data []byte, config ExportConfig) error {
+	file, err := createTextAtomic(path, config)
+	if err != nil {
+		return err
+	}
+	return writeAndCommit(file, data)
+}
+
+// writeAndCommit writes data to file and commits it, removing the temp file on failure
+func writeAndCommit(file *atomicFile, data []byte) error {
+	defer file.Abort()
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write to '%s': %w", file.path, err)
+	}
+	return file.Commit()
+}
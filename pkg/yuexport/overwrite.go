@@ -0,0 +1,200 @@
+package yuexport
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// plannedOutputs lists the files an export with config will write, without writing any of them
+func plannedOutputs(config ExportConfig) ([]string, error) {
+	var paths []string
+	addOutput := func(category, suffix string) error {
+		path, err := resolveOutputPath(config, category, suffix)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	}
+
+	if stageEnabled(config, "root") {
+		files, err := findRootFiles(config.RootPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, suffix := range slices.Sorted(maps.Keys(files)) {
+			if err := addOutput("roots", suffix); err != nil {
+				return nil, err
+			}
+			if config.ExportPinyin {
+				if err := addOutput("roots_pinyin", suffix); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Quick and pop files produce words and chars outputs for the main dict and each suffixed dict
+	for _, fileType := range []string{"quick", "pop"} {
+		if !stageEnabled(config, fileType) {
+			continue
+		}
+		var suffixes []string
+		if _, err := os.Stat(mainDictPath(config.YuhaoPath, config.MethodName, fileType)); err == nil {
+			suffixes = append(suffixes, "")
+		}
+		suffixes = append(suffixes, slices.Sorted(maps.Keys(findSuffixedFiles(config.YuhaoPath, config.MethodName, fileType)))...)
+		for _, suffix := range suffixes {
+			if config.Combine != "only" {
+				if err := addOutput(fileType+"_words", suffix); err != nil {
+					return nil, err
+				}
+				if err := addOutput(fileType+"_chars", suffix); err != nil {
+					return nil, err
+				}
+			}
+			if config.Combine != "" {
+				if err := addOutput(fileType, suffix); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if stageEnabled(config, "template") {
+		templateDir, err := templateSourceDir(config)
+		if err != nil {
+			return nil, err
+		}
+		var templates []string
+		if _, err := os.Stat(filepath.Join(templateDir, config.MethodName+".template.toml")); err == nil {
+			templates = append(templates, templateOutputPath(config, config.MethodName+".toml"))
+		}
+		for _, suffix := range slices.Sorted(maps.Keys(findSuffixedTemplates(templateDir, config.MethodName, "template.toml"))) {
+			templates = append(templates, templateOutputPath(config, config.MethodName+"_"+suffix+".toml"))
+		}
+		paths = append(paths, templates...)
+		if config.ExtractHelp {
+			for _, path := range templates {
+				paths = append(paths, helpOutputPath(path))
+			}
+		}
+	}
+
+	if config.Strict {
+		paths = append(paths, filepath.Join(config.TargetPath, "rejected.txt"))
+	}
+	if config.FreqReport != "" {
+		paths = append(paths, config.FreqReport)
+	}
+	if config.BundlePath != "" {
+		paths = append(paths, config.BundlePath)
+	}
+	if config.SQLitePath != "" {
+		paths = append(paths, config.SQLitePath)
+	}
+	if config.ZipOutput != "" {
+		paths = append(paths, config.ZipOutput)
+	}
+	return paths, nil
+}
+
+// checkOverwrite fails with the list of planned outputs that already exist
+func checkOverwrite(config ExportConfig) error {
+	paths, err := plannedOutputs(config)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return fmt.Errorf("refusing to overwrite existing files (pass --overwrite to replace them):\n  %s", strings.Join(conflicts, "\n  "))
+}
+
+// outputStages returns the stages writing name into the target directory: the stage of a category text file,
+// quick and pop for rejected.txt, or template for a template or help output of method in any version or format
+// It returns nil for names that are not export outputs
+func outputStages(name, method string) []string {
+	if category, _, ok := parseOutputFileName(name); ok {
+		stage, _, _ := strings.Cut(category, "_")
+		if stage == "roots" {
+			stage = "root"
+		}
+		return []string{stage}
+	}
+	if name == "rejected.txt" {
+		return []string{"quick", "pop"}
+	}
+	ext := filepath.Ext(name)
+	if ext == ".html" && strings.HasSuffix(name, "_help.html") {
+		ext = "_help.html"
+	} else if ext != ".toml" && ext != ".yaml" {
+		return nil
+	}
+	base := strings.TrimSuffix(name, ext)
+	if strings.Contains(base, ".template") {
+		return nil
+	}
+	if base == method || strings.HasPrefix(base, method+"_") {
+		return []string{"template"}
+	}
+	return nil
+}
+
+// allStagesEnabled reports whether every one of stages runs with config
+func allStagesEnabled(config ExportConfig, stages []string) bool {
+	for _, stage := range stages {
+		if !stageEnabled(config, stage) {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanOutputs removes output files left in the target directory by earlier runs that this run will not write
+// Only recognized output names of the enabled stages are removed, so unrelated files and the outputs of
+// stages excluded with --only or --skip are kept
+func cleanOutputs(config ExportConfig) error {
+	planned, err := plannedOutputs(config)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(planned))
+	for _, path := range planned {
+		keep[filepath.Clean(path)] = true
+	}
+
+	entries, err := os.ReadDir(config.TargetPath)
+	if err != nil {
+		return fmt.Errorf("failed to read target directory '%s': %w", config.TargetPath, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(config.TargetPath, entry.Name())
+		if !entry.Type().IsRegular() || keep[path] {
+			continue
+		}
+		stages := outputStages(entry.Name(), config.MethodName)
+		if len(stages) == 0 || !allStagesEnabled(config, stages) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale output '%s': %w", path, err)
+		}
+		slog.Info("removed stale output", "file", path)
+	}
+	return nil
+}
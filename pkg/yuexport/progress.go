@@ -0,0 +1,71 @@
+package yuexport
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressLogInterval is how often progress lines are printed when stderr is not a terminal;
+// steps finishing sooner print nothing
+const progressLogInterval = 5 * time.Second
+
+// progressBarWidth is the number of cells in the terminal progress bar
+const progressBarWidth = 30
+
+// progress reports how far a long step has come on stderr:
+// a redrawn bar on a terminal, periodic plain lines otherwise
+// A nil *progress ignores all calls
+type progress struct {
+	w       io.Writer
+	label   string
+	total   int
+	done    int
+	tty     bool
+	lastLog time.Time
+}
+
+// newProgress starts reporting a step of total units, or returns nil when quiet
+func newProgress(label string, total int, quiet bool) *progress {
+	if quiet || total <= 0 {
+		return nil
+	}
+	return &progress{
+		w:       os.Stderr,
+		label:   label,
+		total:   total,
+		tty:     term.IsTerminal(int(os.Stderr.Fd())),
+		lastLog: time.Now(),
+	}
+}
+
+// Add advances the progress by n units
+func (p *progress) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.done = min(p.done+n, p.total)
+
+	if p.tty {
+		filled := p.done * progressBarWidth / p.total
+		fmt.Fprintf(p.w, "\r%s [%s%s] %d/%d", p.label,
+			strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled), p.done, p.total)
+		return
+	}
+	if time.Since(p.lastLog) >= progressLogInterval {
+		fmt.Fprintf(p.w, "%s: %d/%d\n", p.label, p.done, p.total)
+		p.lastLog = time.Now()
+	}
+}
+
+// Finish clears the terminal progress bar
+func (p *progress) Finish() {
+	if p == nil || !p.tty {
+		return
+	}
+	fmt.Fprint(p.w, "\r\033[K")
+}
@@ -0,0 +1,60 @@
+package yuexport
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// writeSQLite stores every exported entry in the entries table of an SQLite database
+// The table is recreated on each run so the database mirrors the latest export
+func writeSQLite(dbPath string, files []FileStats) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database '%s': %w", dbPath, err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`DROP TABLE IF EXISTS entries`,
+		`CREATE TABLE entries (
+			category TEXT NOT NULL,
+			suffix   TEXT NOT NULL,
+			code     TEXT NOT NULL,
+			word     TEXT NOT NULL
+		)`,
+		`CREATE INDEX idx_entries_code ON entries (code)`,
+		`CREATE INDEX idx_entries_category ON entries (category, suffix)`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO entries (category, suffix, code, word) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insert.Close()
+
+	for _, file := range files {
+		for _, entry := range file.written {
+			if _, err := insert.Exec(file.Category, file.Suffix, entry[0], entry[1]); err != nil {
+				return fmt.Errorf("failed to insert into '%s': %w", dbPath, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package yuexport
+
+import (
+	"sort"
+)
+
+// OutputReport summarizes the entries of one export output file
+type OutputReport struct {
+	File              string      `json:"file"`
+	Entries           int         `json:"entries"`
+	Codes             int         `json:"codes"`
+	CodeLengths       map[int]int `json:"code_lengths"`
+	MultiWordCodes    int         `json:"multi_word_codes"`
+	LongestCode       string      `json:"longest_code"`
+	MostCollidedCode  string      `json:"most_collided_code"`
+	MostCollidedWords int         `json:"most_collided_words"`
+}
+
+// ExportReport summarizes the output files of an export directory
+type ExportReport struct {
+	Entries int            `json:"entries"`
+	Files   []OutputReport `json:"files"`
+}
+
+// AnalyzeExport reads the recognized output files of an export directory, or zip file of one, and reports
+// their entry counts, code length distribution and code collisions; sep is the separator the export used
+func AnalyzeExport(path, sep string) (ExportReport, error) {
+	var report ExportReport
+	dir, cleanup, err := openExportDir(path)
+	if err != nil {
+		return report, err
+	}
+	defer cleanup()
+
+	files, err := listOutputFiles(dir)
+	if err != nil {
+		return report, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		category, _, _ := parseOutputFileName(name)
+		entries, err := readCodeWordFile(files[name], category == "roots" || category == "roots_pinyin", sep, false)
+		if err != nil {
+			return report, err
+		}
+		fileReport := analyzeEntries(groupWordsByCode(entries))
+		fileReport.File = name
+		report.Entries += fileReport.Entries
+		report.Files = append(report.Files, fileReport)
+	}
+	return report, nil
+}
+
+// analyzeEntries builds the report of one file from its words grouped by code
+// Ties for the longest and most collided code go to the first code in sortByCode order
+func analyzeEntries(codes map[string][]string) OutputReport {
+	report := OutputReport{Codes: len(codes), CodeLengths: make(map[int]int)}
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sortCodes(sorted)
+
+	for _, code := range sorted {
+		words := len(codes[code])
+		report.Entries += words
+		report.CodeLengths[len(code)]++
+		if words > 1 {
+			report.MultiWordCodes++
+		}
+		if len(code) > len(report.LongestCode) {
+			report.LongestCode = code
+		}
+		if words > report.MostCollidedWords {
+			report.MostCollidedCode, report.MostCollidedWords = code, words
+		}
+	}
+	return report
+}
@@ -0,0 +1,107 @@
+package yuexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long watch mode waits for further changes before re-exporting
+const watchDebounce = 500 * time.Millisecond
+
+// WatchExport runs the export once, then re-runs it whenever dict files or templates change
+// config locates the dict directory as in Export (SchemaConfigFile, YuhaoDir)
+// Only directory sources can be watched, without config.Update; it keeps running until ctx is cancelled, which also aborts a run in progress
+func WatchExport(ctx context.Context, src string, config ExportConfig, run func(ctx context.Context) error) error {
+	if !isDirSource(src) {
+		return fmt.Errorf("--watch requires an extracted source directory, got: %s", src)
+	}
+	// Each run would rewrite the watched templates with a bumped config_version and trigger the next run
+	if config.Update {
+		return errors.New("--watch cannot be combined with --update")
+	}
+
+	if config.SchemaConfigFile == "" {
+		config.SchemaConfigFile = schemaConfigFile
+	}
+	if config.YuhaoDir == "" {
+		config.YuhaoDir = defaultYuhaoDir
+	}
+	root, _, err := readSchemaRoot(src, config.SchemaConfigFile, "")
+	if err != nil {
+		return err
+	}
+	yuhaoDir := filepath.Join(root, filepath.FromSlash(config.YuhaoDir))
+	schemaDir := filepath.Dir(filepath.Join(root, filepath.FromSlash(config.SchemaConfigFile)))
+	templateDir := config.TemplateDir
+	if templateDir == "" {
+		if templateDir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	for _, dir := range []string{yuhaoDir, schemaDir, templateDir} {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch directory '%s': %w", dir, err)
+		}
+	}
+
+	rerun := func() {
+		slog.Info("exporting", "source", src)
+		if err := run(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("export failed, waiting for changes", "error", err)
+		}
+	}
+	rerun()
+
+	// A nil channel blocks until the debounce timer is armed by a relevant change
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !isWatchedFile(event.Name, yuhaoDir) {
+				continue
+			}
+			slog.Debug("source file changed", "file", event.Name, "op", event.Op.String())
+			debounce = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("file watcher error", "error", err)
+		case <-debounce:
+			debounce = nil
+			rerun()
+		}
+	}
+}
+
+// isWatchedFile reports whether a change to name should trigger a re-export:
+// any dict file in the dict directory, or a template in any watched directory
+func isWatchedFile(name, yuhaoDir string) bool {
+	if isIgnoredFile(filepath.Base(name)) {
+		return false
+	}
+	if strings.HasSuffix(name, ".template.toml") {
+		return true
+	}
+	return filepath.Dir(name) == filepath.Clean(yuhaoDir) && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yaml.gz"))
+}
@@ -81,9 +81,9 @@ for zip_file in ./inputs/*.zip; do
         fi
 
         if [ "$DO_DEV" = true ]; then
-            go run ./main.go ./export.go export -s "$zip_file" -r "$csv_file" -t ./export
+            go run . export -s "$zip_file" -r "$csv_file" -t ./export
         else
-            go run ./main.go ./export.go export -s "$zip_file" -r "$csv_file" -t ./export -u
+            go run . export -s "$zip_file" -r "$csv_file" -t ./export -u
         fi
 
         cp ./export/*.toml ./publish/
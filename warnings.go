@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// warningLog collects the warnings logged during a command for --fail-on-warnings
+type warningLog struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (w *warningLog) add(warning string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warnings = append(w.warnings, warning)
+}
+
+// err returns nil without warnings, otherwise an error listing them
+func (w *warningLog) err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.warnings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s\n  %s", msg("warnings-failed", len(w.warnings)), strings.Join(w.warnings, "\n  "))
+}
+
+// warningHandler passes records on to its handler and records every warning in log,
+// including warnings below the handler's level, so --fail-on-warnings works without --log-level warn
+type warningHandler struct {
+	slog.Handler
+	log   *warningLog
+	attrs []slog.Attr
+}
+
+func (h *warningHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level == slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h *warningHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level == slog.LevelWarn {
+		var b strings.Builder
+		b.WriteString(record.Message)
+		writeAttr := func(attr slog.Attr) bool {
+			fmt.Fprintf(&b, " %s=%v", attr.Key, attr.Value)
+			return true
+		}
+		for _, attr := range h.attrs {
+			writeAttr(attr)
+		}
+		record.Attrs(writeAttr)
+		h.log.add(b.String())
+	}
+	if !h.Handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *warningHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &warningHandler{Handler: h.Handler.WithAttrs(attrs), log: h.log, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *warningHandler) WithGroup(name string) slog.Handler {
+	return &warningHandler{Handler: h.Handler.WithGroup(name), log: h.log, attrs: h.attrs}
+}